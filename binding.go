@@ -0,0 +1,225 @@
+package ctxboot
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+var (
+	durationType        = reflect.TypeOf(time.Duration(0))
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// SetConfigPrefix makes the component registered for typ a config component:
+// InitializeComponents sets each of its exported fields from the property
+// named after the prefix and the field, e.g. db.max-open-conns for the
+// MaxOpenConns field with prefix db. Nested structs extend the prefix, and
+// fields without a property keep their value
+func (c *CtxbootComponentContext) SetConfigPrefix(typ reflect.Type, prefix string) error {
+	return c.SetNamedConfigPrefix("", typ, prefix)
+}
+
+// SetNamedConfigPrefix is like SetConfigPrefix for a component registered
+// under a qualifier name
+func (c *CtxbootComponentContext) SetNamedConfigPrefix(name string, typ reflect.Type, prefix string) error {
+	key := componentKey{typ: typ, name: name}
+
+	c.mu.Lock()
+	reinject, err := c.checkRegistration(key)
+	if err != nil {
+		c.mu.Unlock()
+		return err
+	}
+	if !c.registered(key) {
+		c.mu.Unlock()
+		return newError(ErrComponentNotFound, key, "cannot set config prefix")
+	}
+	c.configs[key] = prefix
+	instance, exists := c.components[key]
+	_, isProvided := c.providers[key]
+	c.mu.Unlock()
+
+	if !reinject {
+		return nil
+	}
+
+	// The initialized instance is bound again, and injected again into the
+	// components holding a copy of it
+	if exists && !isProvided {
+		if err := c.bindProperties(key, instance); err != nil {
+			return withPath(err, key.String())
+		}
+	}
+	return c.refreshDependents(key)
+}
+
+// bindProperties binds a config component to the properties under its
+// prefix, if it has one
+func (c *CtxbootComponentContext) bindProperties(key componentKey, instance interface{}) error {
+	c.mu.RLock()
+	prefix, ok := c.configs[key]
+	c.mu.RUnlock()
+
+	val := reflect.ValueOf(instance)
+	if !ok || val.Kind() != reflect.Ptr || val.Elem().Kind() != reflect.Struct {
+		return nil
+	}
+	return bindStruct(c.properties(), prefix, val.Elem())
+}
+
+// bindStruct sets the exported fields of a struct value from the properties
+// under prefix. Fields with a ctxboot tag are left to injectDependencies
+func bindStruct(props *Properties, prefix string, elem reflect.Value) error {
+	typ := elem.Type()
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		if _, ok := field.Tag.Lookup("ctxboot"); ok {
+			continue
+		}
+
+		key := propertyName(field.Name)
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+
+		// Nested structs are bound under their own prefix
+		if field.Type.Kind() == reflect.Struct && !isScalar(field.Type) {
+			if err := bindStruct(props, key, elem.Field(i)); err != nil {
+				return err
+			}
+			continue
+		}
+
+		raw, ok := props.Property(key)
+		if !ok {
+			continue
+		}
+		value, err := convertProperty(raw, field.Type)
+		if err != nil {
+			return withPath(propertyError(key, raw, err), fieldStep(typ, field.Name))
+		}
+		elem.Field(i).Set(value)
+	}
+	return nil
+}
+
+// propertyValue resolves the value of a field tagged with opts
+func (c *CtxbootComponentContext) propertyValue(fieldType reflect.Type, opts valueTag) (reflect.Value, error) {
+	raw, ok := c.properties().Property(opts.key)
+	if !ok {
+		if !opts.hasDefault {
+			return reflect.Value{}, &Error{Err: ErrMissingProperty, Detail: opts.key}
+		}
+		raw = opts.def
+	}
+
+	value, err := convertProperty(raw, fieldType)
+	if err != nil {
+		return reflect.Value{}, propertyError(opts.key, raw, err)
+	}
+	return value, nil
+}
+
+// propertyError reports a property value that does not convert to its field
+func propertyError(key, raw string, err error) *Error {
+	return &Error{Err: ErrNotAssignable, Detail: fmt.Sprintf("property %s=%q: %v", key, raw, err)}
+}
+
+// propertyName returns the property key of a config field, e.g.
+// max-open-conns for MaxOpenConns and http-port for HTTPPort
+func propertyName(field string) string {
+	runes := []rune(field)
+	var b strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) && i > 0 &&
+			(unicode.IsLower(runes[i-1]) || (i+1 < len(runes) && unicode.IsLower(runes[i+1]))) {
+			b.WriteByte('-')
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
+
+// isScalar reports whether values of typ are converted from a single
+// property rather than bound field by field
+func isScalar(typ reflect.Type) bool {
+	return reflect.PtrTo(typ).Implements(textUnmarshalerType)
+}
+
+// convertProperty converts a property value to typ: strings, booleans,
+// numbers, durations, encoding.TextUnmarshaler implementations, pointers to
+// them, and slices of them given as comma separated values
+func convertProperty(raw string, typ reflect.Type) (reflect.Value, error) {
+	value := reflect.New(typ).Elem()
+
+	if isScalar(typ) {
+		err := value.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(raw))
+		return value, err
+	}
+	if typ == durationType {
+		d, err := time.ParseDuration(raw)
+		value.SetInt(int64(d))
+		return value, err
+	}
+
+	switch typ.Kind() {
+	case reflect.String:
+		value.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return value, err
+		}
+		value.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 0, typ.Bits())
+		if err != nil {
+			return value, err
+		}
+		value.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(raw, 0, typ.Bits())
+		if err != nil {
+			return value, err
+		}
+		value.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(raw, typ.Bits())
+		if err != nil {
+			return value, err
+		}
+		value.SetFloat(f)
+	case reflect.Ptr:
+		elem, err := convertProperty(raw, typ.Elem())
+		if err != nil {
+			return value, err
+		}
+		ptr := reflect.New(typ.Elem())
+		ptr.Elem().Set(elem)
+		value.Set(ptr)
+	case reflect.Slice:
+		if raw == "" {
+			return reflect.MakeSlice(typ, 0, 0), nil
+		}
+		items := strings.Split(raw, ",")
+		value = reflect.MakeSlice(typ, len(items), len(items))
+		for i, item := range items {
+			elem, err := convertProperty(strings.TrimSpace(item), typ.Elem())
+			if err != nil {
+				return value, err
+			}
+			value.Index(i).Set(elem)
+		}
+	default:
+		return value, fmt.Errorf("unsupported type %v", typ)
+	}
+	return value, nil
+}
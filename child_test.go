@@ -0,0 +1,162 @@
+package ctxboot
+
+import (
+	"reflect"
+	"testing"
+)
+
+type chStore interface {
+	Load() string
+}
+
+type chPG struct{}
+
+func (*chPG) Load() string { return "pg" }
+
+type chMem struct{}
+
+func (*chMem) Load() string { return "mem" }
+
+type chLogger struct{ prefix string }
+
+type chService struct {
+	Store  chStore   `ctxboot:"inject"`
+	Logger *chLogger `ctxboot:"inject"`
+	Audit  *chLogger `ctxboot:"inject,name=audit"`
+	Port   int       `ctxboot:"value=port,default=80"`
+}
+
+type chHandler interface {
+	Handle() string
+}
+
+type chHandlerA struct{ name string }
+
+func (h *chHandlerA) Handle() string { return h.name }
+
+type chHandlerB struct{ name string }
+
+func (h *chHandlerB) Handle() string { return h.name }
+
+type chHandlerC struct{ name string }
+
+func (h *chHandlerC) Handle() string { return h.name }
+
+type chRouter struct {
+	Handlers []chHandler          `ctxboot:"inject"`
+	ByName   map[string]chHandler `ctxboot:"inject"`
+}
+
+func TestChildFallback(t *testing.T) {
+	parent := NewCtxbootComponentContext()
+	parent.SetProperties(NewProperties(MapSource(map[string]string{"port": "8080"})))
+	parent.SetProfiles("test")
+	pg, logger, audit := &chPG{}, &chLogger{prefix: "app"}, &chLogger{prefix: "audit"}
+	mustRegister(t, parent, pg)
+	mustRegister(t, parent, logger)
+	if err := parent.SetNamedComponent("audit", reflect.TypeOf(audit), audit); err != nil {
+		t.Fatal(err)
+	}
+	if err := parent.InitializeComponents(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The child overrides the store and falls back to its parent for the
+	// loggers and the properties
+	child := parent.NewChild()
+	mem, service := &chMem{}, &chService{}
+	mustRegister(t, child, mem)
+	mustRegister(t, child, service)
+	if err := child.InitializeComponents(); err != nil {
+		t.Fatal(err)
+	}
+	if service.Store != mem || service.Logger != logger || service.Audit != audit || service.Port != 8080 {
+		t.Errorf("service = %+v, want the child store, the parent loggers and port", service)
+	}
+	if got := MustGet[chStore](child); got != mem {
+		t.Errorf("child Get() = %v, want the child store", got)
+	}
+	if got, err := child.GetNamedComponent("audit", reflect.TypeOf(audit)); err != nil || got != audit {
+		t.Errorf("child GetNamedComponent() = %v, %v, want the parent component", got, err)
+	}
+	if !reflect.DeepEqual(child.ActiveProfiles(), []string{"test"}) {
+		t.Errorf("child ActiveProfiles() = %v, want the parent profiles", child.ActiveProfiles())
+	}
+
+	// The parent is left untouched
+	if got := MustGet[chStore](parent); got != pg {
+		t.Errorf("parent Get() = %v, want the parent store", got)
+	}
+	if _, err := Get[*chService](parent); !isMissing(err) {
+		t.Errorf("parent Get(*chService) error = %v, want not found", err)
+	}
+}
+
+func TestChildCollection(t *testing.T) {
+	parent := NewCtxbootComponentContext()
+	a, b := &chHandlerA{name: "a"}, &chHandlerB{name: "parent b"}
+	mustRegister(t, parent, a)
+	mustRegister(t, parent, b)
+
+	// Child entries come first for equal orders, those overriding a parent
+	// entry replacing it
+	child := parent.NewChild()
+	childB, c, router := &chHandlerB{name: "child b"}, &chHandlerC{name: "c"}, &chRouter{}
+	mustRegister(t, child, childB)
+	mustRegister(t, child, c)
+	mustRegister(t, child, router)
+	if err := child.SetOrder(reflect.TypeOf(c), 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := child.InitializeComponents(); err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []chHandler{childB, a, c}; !reflect.DeepEqual(router.Handlers, want) {
+		t.Errorf("Handlers = %v, want %v", router.Handlers, want)
+	}
+	want := map[string]chHandler{"*ctxboot.chHandlerA": a, "*ctxboot.chHandlerB": childB, "*ctxboot.chHandlerC": c}
+	if !reflect.DeepEqual(router.ByName, want) {
+		t.Errorf("ByName = %v, want %v", router.ByName, want)
+	}
+}
+
+// chGenerated is shaped like the generated ComponentContext
+type chGenerated struct {
+	*CtxbootComponentContext
+}
+
+func (c *chGenerated) NewChild() *chGenerated {
+	return &chGenerated{c.CtxbootComponentContext.NewChild()}
+}
+
+func (c *chGenerated) GetLogger() (*chLogger, error) {
+	component, err := c.GetComponent(reflect.TypeOf((*chLogger)(nil)))
+	if err != nil {
+		return nil, err
+	}
+	return component.(*chLogger), nil
+}
+
+func TestGeneratedChild(t *testing.T) {
+	parent := &chGenerated{NewCtxbootComponentContext()}
+	logger := &chLogger{prefix: "parent"}
+	mustRegister(t, parent.CtxbootComponentContext, logger)
+
+	child := parent.NewChild()
+	if got, err := child.GetLogger(); err != nil || got != logger {
+		t.Errorf("child GetLogger() = %v, %v, want the parent component", got, err)
+	}
+	if got := MustGet[*chLogger](child); got != logger {
+		t.Errorf("child Get() = %v, want the parent component", got)
+	}
+
+	override := &chLogger{prefix: "child"}
+	mustRegister(t, child.CtxbootComponentContext, override)
+	if got, err := child.GetLogger(); err != nil || got != override {
+		t.Errorf("child GetLogger() = %v, %v, want the child component", got, err)
+	}
+	if got, err := parent.GetLogger(); err != nil || got != logger {
+		t.Errorf("parent GetLogger() = %v, %v, want the parent component", got, err)
+	}
+}
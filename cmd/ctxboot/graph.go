@@ -0,0 +1,220 @@
+package main
+
+import (
+	"go/ast"
+	"path"
+	"strings"
+
+	"github.com/iondodon/ctxboot"
+)
+
+// typeMethods collects the method names of the scanned types, to find the
+// components implementing the scanned interfaces without type checking
+type typeMethods struct {
+	methods    map[string]map[string]bool // methods by qualified receiver type
+	interfaces map[string][]string        // methods by qualified interface
+}
+
+func newTypeMethods() *typeMethods {
+	return &typeMethods{
+		methods:    make(map[string]map[string]bool),
+		interfaces: make(map[string][]string),
+	}
+}
+
+// addMethod records a method declaration of package pkg
+func (m *typeMethods) addMethod(funcDecl *ast.FuncDecl, pkg string) {
+	if funcDecl.Recv == nil || len(funcDecl.Recv.List) == 0 {
+		return
+	}
+	recv := baseType(funcDecl.Recv.List[0].Type)
+	switch t := recv.(type) {
+	case *ast.IndexExpr:
+		recv = t.X
+	case *ast.IndexListExpr:
+		recv = t.X
+	}
+	ident, ok := recv.(*ast.Ident)
+	if !ok {
+		return
+	}
+
+	name := qualifiedName(pkg, ident.Name)
+	if m.methods[name] == nil {
+		m.methods[name] = make(map[string]bool)
+	}
+	m.methods[name][funcDecl.Name.Name] = true
+}
+
+// addInterface records the methods of an interface of package pkg. Embedded
+// interfaces are not followed
+func (m *typeMethods) addInterface(name string, iface *ast.InterfaceType) {
+	methods := []string{}
+	for _, method := range iface.Methods.List {
+		for _, ident := range method.Names {
+			methods = append(methods, ident.Name)
+		}
+	}
+	m.interfaces[name] = methods
+}
+
+// implements reports whether the scanned type typeName has all the methods
+// of the scanned interface ifaceName
+func (m *typeMethods) implements(typeName, ifaceName string) bool {
+	for _, method := range m.interfaces[ifaceName] {
+		if !m.methods[typeName][method] {
+			return false
+		}
+	}
+	return true
+}
+
+// graphID returns the ID of a node as the runtime context names it, e.g.
+// *database.Pool(replica) or database.Database
+func graphID(pkg, name, qualifier string, pointer bool) string {
+	id := pkg + "." + name
+	if pointer {
+		id = "*" + id
+	}
+	if qualifier != "" {
+		id += "(" + qualifier + ")"
+	}
+	return id
+}
+
+// buildGraph builds the dependency graph of the scanned components, in the
+// same form as ctxboot.CtxbootComponentContext.Graph. typeFiles holds the
+// files declaring the scanned types and packagePath returns the import path
+// of the package of a scanned file
+func buildGraph(components []Component, types *typeMethods, typeFiles map[string]string, packagePath func(file string) string) *ctxboot.Graph {
+	g := &ctxboot.Graph{Version: ctxboot.GraphVersion}
+
+	// typePackage returns the import path of a scanned type, if known
+	typePackage := func(name string) string {
+		if !strings.Contains(name, ".") {
+			return "main"
+		}
+		if file, ok := typeFiles[name]; ok {
+			return packagePath(file)
+		}
+		return ""
+	}
+
+	// Nodes of the components, by qualified type name
+	ids := make(map[string]string, len(components))
+	byType := make(map[string][]Component)
+	for _, c := range components {
+		node := ctxboot.GraphNode{
+			Kind:      ctxboot.NodeComponent,
+			Package:   typePackage(c.qualifiedName()),
+			Qualifier: c.Qualifier,
+			Scope:     "singleton",
+			Primary:   c.Primary,
+		}
+		pkg, pointer := c.Package, true
+		if c.Provider != "" {
+			node.Kind = ctxboot.NodeProvider
+			pkg = c.ResultPackage
+			_, pointer = c.ResultType.(*ast.StarExpr)
+			// Types of imported packages are named after the package, not
+			// the import name used by the provider's file
+			if importPath, ok := c.FileImports[pkg]; ok {
+				node.Package = importPath
+				pkg = path.Base(importPath)
+			}
+		}
+		if c.Scope == "prototype" {
+			node.Scope = "prototype"
+		}
+		node.ID = graphID(pkg, c.Name, c.Qualifier, pointer)
+		node.Type = graphID(pkg, c.Name, "", pointer)
+		g.AddNode(node)
+
+		ids[componentID(c)] = node.ID
+		byType[c.qualifiedName()] = append(byType[c.qualifiedName()], c)
+	}
+
+	// implementations returns the components implementing an interface
+	implementations := func(iface string) []Component {
+		var impls []Component
+		for _, c := range components {
+			if c.qualifiedName() != iface && types.implements(c.qualifiedName(), iface) {
+				impls = append(impls, c)
+			}
+		}
+		return impls
+	}
+
+	requested := make(map[string]bool) // interfaces requested without qualifier
+	for _, c := range components {
+		from := ids[componentID(c)]
+		for _, dep := range c.Dependencies {
+			edge := ctxboot.GraphEdge{
+				From:       from,
+				Kind:       ctxboot.EdgeInject,
+				Field:      dep.Field,
+				Qualifier:  dep.Qualifier,
+				Collection: dep.Collection,
+				Optional:   dep.Optional,
+				Deferred:   dep.Deferred,
+			}
+			name := qualifiedName(dep.Package, dep.Name)
+			_, isInterface := types.interfaces[name]
+
+			// Interfaces requested as such point to the interface node
+			if isInterface && dep.Qualifier == "" && len(byType[name]) == 0 {
+				requested[name] = true
+				edge.To = graphID(dep.Package, dep.Name, "", false)
+				g.AddEdge(edge)
+				continue
+			}
+
+			// Otherwise point to the injected components, or to all the
+			// matching ones when they are ambiguous
+			targets, _ := dependencyTargets(byType[name], dep, true)
+			if len(targets) == 0 && isInterface {
+				targets, _ = dependencyTargets(implementations(name), dep, false)
+			}
+
+			if len(targets) == 0 {
+				edge.To = graphID(dep.Package, dep.Name, dep.Qualifier, !isInterface)
+				g.AddNode(ctxboot.GraphNode{
+					ID:        edge.To,
+					Kind:      ctxboot.NodeMissing,
+					Type:      graphID(dep.Package, dep.Name, "", !isInterface),
+					Package:   typePackage(name),
+					Qualifier: dep.Qualifier,
+				})
+				g.AddEdge(edge)
+			}
+			for _, t := range targets {
+				edge.To = ids[componentID(t)]
+				g.AddEdge(edge)
+			}
+		}
+	}
+
+	// Link the requested interfaces to their implementations
+	for name := range requested {
+		pkg, iface, found := strings.Cut(name, ".")
+		if !found {
+			pkg, iface = "main", name
+		}
+		id := graphID(pkg, iface, "", false)
+		g.AddNode(ctxboot.GraphNode{ID: id, Kind: ctxboot.NodeInterface, Type: id, Package: typePackage(name)})
+		for _, impl := range implementations(name) {
+			g.AddEdge(ctxboot.GraphEdge{From: id, To: ids[componentID(impl)], Kind: ctxboot.EdgeImplements})
+		}
+	}
+
+	return g
+}
+
+// componentID identifies a scanned component by type and qualifier, e.g.
+// database.Pool(replica)
+func componentID(c Component) string {
+	if c.Qualifier == "" {
+		return c.qualifiedName()
+	}
+	return c.qualifiedName() + "(" + c.Qualifier + ")"
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"bytes"
+	"flag"
 	"fmt"
 	"go/ast"
 	"go/parser"
@@ -9,9 +10,12 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"reflect"
 	"sort"
+	"strconv"
 	"strings"
 	"text/template"
+	"unicode"
 )
 
 type Import struct {
@@ -25,22 +29,99 @@ type Component struct {
 	File         string
 	Dependencies []Dependency
 	Alias        string
+	Qualifier    string
+	Primary      bool
+	Order        string
+	Scope        string
+	Config       bool   // bound to properties, see //ctxboot:config
+	Prefix       string // property prefix of a config component
+	Conditions
+
+	// Provider components are created by a //ctxboot:provider function
+	Provider      string            // name of the provider function
+	Type          string            // provided type as written in the generated code
+	ResultType    ast.Expr          // provided type as written in the provider's file
+	ResultPackage string            // package of the provided type
+	FileImports   map[string]string // imports of the provider's file, by name
+}
+
+// Conditions restrict the registration of a component to matching
+// environments, see ctxboot.Condition
+type Conditions struct {
+	Profile       string
+	OnEnv         string
+	OnProperty    string
+	OnMissing     string // type as written in the annotation, e.g. database.Database
+	OnMissingType string // reflect.Type of OnMissing in the generated code
+}
+
+// Conditional reports whether the component is registered conditionally
+func (c Conditions) Conditional() bool {
+	return c.Profile != "" || c.OnEnv != "" || c.OnProperty != "" || c.OnMissing != ""
+}
+
+// Literal returns the ctxboot.Condition of the component in the generated code
+func (c Conditions) Literal() string {
+	var fields []string
+	if c.Profile != "" {
+		fields = append(fields, fmt.Sprintf("Profile: %q", c.Profile))
+	}
+	if c.OnEnv != "" {
+		fields = append(fields, fmt.Sprintf("OnEnv: %q", c.OnEnv))
+	}
+	if c.OnProperty != "" {
+		fields = append(fields, fmt.Sprintf("OnProperty: %q", c.OnProperty))
+	}
+	if c.OnMissingType != "" {
+		fields = append(fields, "OnMissing: "+c.OnMissingType)
+	}
+	return "ctxboot.Condition{" + strings.Join(fields, ", ") + "}"
 }
 
 type Dependency struct {
+	Name       string
+	Package    string
+	File       string
+	Qualifier  string
+	Collection bool
+	Optional   bool
+	Deferred   bool   // injected as ctxboot.Lazy or ctxboot.Provider
+	Field      string // injected field, or provider parameter as #i
+}
+
+// Decorator is a //ctxboot:decorator function wrapping the components
+// injected as the interface it takes and returns
+type Decorator struct {
 	Name    string
 	Package string
 	File    string
+	Alias   string
+	Order   string
+	Conditions
 }
 
 type ComponentInfo struct {
 	Package    string
 	Components []Component
+	Decorators []Decorator
 	Imports    []Import
 	ModulePath string
 }
 
-const registrationTemplate = `// Code generated by ctxboot; DO NOT EDIT.
+// templateImports are the packages imported by every generated file, by path
+var templateImports = map[string]string{
+	"github.com/iondodon/ctxboot": "ctxboot",
+	"reflect":                     "reflect",
+	"log":                         "log",
+	"fmt":                         "fmt",
+}
+
+const registrationTemplate = `
+{{- define "pkg"}}{{if ne .Package "main"}}{{if .Alias}}{{.Alias}}.{{else}}{{.Package}}.{{end}}{{end}}{{end}}
+{{- define "type"}}{{if .Provider}}{{.Type}}{{else}}{{template "pkg" .}}{{.Name}}{{end}}{{end}}
+{{- define "goType"}}{{if .Provider}}{{.Type}}{{else}}*{{template "type" .}}{{end}}{{end}}
+{{- define "reflectType"}}reflect.TypeOf((*{{template "type" .}})(nil)){{if .Provider}}.Elem(){{end}}{{end -}}
+// Code generated by ctxboot; DO NOT EDIT.
 
 package main
 
@@ -67,37 +148,114 @@ func (c *ComponentContext) RegisterComponent(instance interface{}) error {
 	return c.SetComponent(reflect.TypeOf(instance), instance)
 }
 
+// RegisterNamed registers a component instance under a qualifier name
+func (c *ComponentContext) RegisterNamed(name string, instance interface{}) error {
+	if instance == nil {
+		return fmt.Errorf("cannot register nil component")
+	}
+	return c.SetNamedComponent(name, reflect.TypeOf(instance), instance)
+}
+
+// NewChild creates a child context that overrides components of c and falls back to c for the others
+func (c *ComponentContext) NewChild() *ComponentContext {
+	return &ComponentContext{c.CtxbootComponentContext.NewChild()}
+}
+
 // registerScanedComponenets registers all components
 func (c *ComponentContext) registerScanedComponenets() error {
 	// Register components in dependency order
 	{{range .Components}}
-	// Register {{if ne .Package "main"}}{{if .Alias}}{{.Alias}}.{{else}}{{.Package}}.{{end}}{{end}}{{.Name}}
-	if err := c.SetComponent(reflect.TypeOf((*{{if ne .Package "main"}}{{if .Alias}}{{.Alias}}.{{else}}{{.Package}}.{{end}}{{end}}{{.Name}})(nil)), &{{if ne .Package "main"}}{{if .Alias}}{{.Alias}}.{{else}}{{.Package}}.{{end}}{{end}}{{.Name}}{}); err != nil {
-		log.Fatalf("Failed to register component %s: %v", "{{if ne .Package "main"}}{{if .Alias}}{{.Alias}}.{{else}}{{.Package}}.{{end}}{{end}}{{.Name}}", err)
+	{{- if .Conditional}}
+	if c.Matches({{.Literal}}) {
+	{{- end}}
+	{{- if .Provider}}
+	// Register provider {{template "pkg" .}}{{.Provider}}{{if .Qualifier}} as "{{.Qualifier}}"{{end}}
+	if err := c.{{if .Qualifier}}SetNamedProvider("{{.Qualifier}}", {{else}}SetProvider({{end}}{{template "pkg" .}}{{.Provider}}); err != nil {
+		log.Fatalf("Failed to register provider %s: %v", "{{template "pkg" .}}{{.Provider}}", err)
+	}
+	{{- else if eq .Scope "prototype"}}
+	// Register prototype {{template "type" .}}{{if .Qualifier}} as "{{.Qualifier}}"{{end}}
+	if err := c.{{if .Qualifier}}SetNamedPrototype("{{.Qualifier}}", {{else}}SetPrototype({{end}}{{template "reflectType" .}}, func() interface{} {
+		return &{{template "type" .}}{}
+	}); err != nil {
+		log.Fatalf("Failed to register prototype %s: %v", "{{template "type" .}}", err)
+	}
+	{{- else}}
+	// Register {{template "type" .}}{{if .Qualifier}} as "{{.Qualifier}}"{{end}}
+	if err := c.{{if .Qualifier}}SetNamedComponent("{{.Qualifier}}", {{else}}SetComponent({{end}}{{template "reflectType" .}}, &{{template "type" .}}{}); err != nil {
+		log.Fatalf("Failed to register component %s: %v", "{{template "type" .}}", err)
+	}
+	{{- end}}
+	{{- if .Order}}
+	if err := c.{{if .Qualifier}}SetNamedOrder("{{.Qualifier}}", {{else}}SetOrder({{end}}{{template "reflectType" .}}, {{.Order}}); err != nil {
+		log.Fatalf("Failed to set order of component %s: %v", "{{template "type" .}}", err)
 	}
+	{{- end}}
+	{{- if .Primary}}
+	if err := c.{{if .Qualifier}}MarkNamedPrimary("{{.Qualifier}}", {{else}}MarkPrimary({{end}}{{template "reflectType" .}}); err != nil {
+		log.Fatalf("Failed to mark component %s as primary: %v", "{{template "type" .}}", err)
+	}
+	{{- end}}
+	{{- if .Config}}
+	if err := c.{{if .Qualifier}}SetNamedConfigPrefix("{{.Qualifier}}", {{else}}SetConfigPrefix({{end}}{{template "reflectType" .}}, "{{.Prefix}}"); err != nil {
+		log.Fatalf("Failed to set config prefix of component %s: %v", "{{template "type" .}}", err)
+	}
+	{{- end}}
+	{{- if .Conditional}}
+	}
+	{{- end}}
+	{{end}}
+	{{- range .Decorators}}
+	{{- if .Conditional}}
+	if c.Matches({{.Literal}}) {
+	{{- end}}
+	// Register decorator {{template "pkg" .}}{{.Name}}
+	if err := c.AddDecorator({{template "pkg" .}}{{.Name}}, {{if .Order}}{{.Order}}{{else}}0{{end}}); err != nil {
+		log.Fatalf("Failed to register decorator %s: %v", "{{template "pkg" .}}{{.Name}}", err)
+	}
+	{{- if .Conditional}}
+	}
+	{{- end}}
 	{{end}}
-	
 	return nil
 }
 
 // NewComponentContext creates a new component context instance and registers all scanned components
 func NewComponentContext() *ComponentContext {
+	return NewComponentContextWith(nil)
+}
+
+// NewComponentContextWith is like NewComponentContext, the conditions of the scanned components being
+// evaluated against the given properties and profiles (by default those listed by ctxboot.ProfilesProperty)
+func NewComponentContextWith(properties *ctxboot.Properties, profiles ...string) *ComponentContext {
 	ctx := &ComponentContext{ctxboot.NewCtxbootComponentContext()}
+	if properties != nil {
+		ctx.SetProperties(properties)
+	}
+	if len(profiles) > 0 {
+		ctx.SetProfiles(profiles...)
+	}
 	if err := ctx.registerScanedComponenets(); err != nil {
 		log.Fatalf("Failed to register scanned components: %v", err)
 	}
 	return ctx
 }
 
+// ValidateComponentContext registers all scanned components and checks their wiring without creating,
+// injecting or initializing any of them, reporting all the problems found, e.g. in a CI check
+func ValidateComponentContext() error {
+	return NewComponentContext().Validate()
+}
+
 // Component getter methods
 {{range .Components}}
-// Get{{.Name}} returns the {{.Name}} component
-func (c *ComponentContext) Get{{.Name}}() (*{{if ne .Package "main"}}{{if .Alias}}{{.Alias}}.{{else}}{{.Package}}.{{end}}{{end}}{{.Name}}, error) {
-	component, err := c.GetComponent(reflect.TypeOf((*{{if ne .Package "main"}}{{if .Alias}}{{.Alias}}.{{else}}{{.Package}}.{{end}}{{end}}{{.Name}})(nil)))
+// Get{{exportName .Qualifier}}{{.Name}} returns the {{if .Qualifier}}"{{.Qualifier}}" {{end}}{{.Name}} component
+func (c *ComponentContext) Get{{exportName .Qualifier}}{{.Name}}() ({{template "goType" .}}, error) {
+	component, err := c.{{if .Qualifier}}GetNamedComponent("{{.Qualifier}}", {{else}}GetComponent({{end}}{{template "reflectType" .}})
 	if err != nil {
 		return nil, err
 	}
-	return component.(*{{if ne .Package "main"}}{{if .Alias}}{{.Alias}}.{{else}}{{.Package}}.{{end}}{{end}}{{.Name}}), nil
+	return component.({{template "goType" .}}), nil
 }
 {{end}}
 `
@@ -119,13 +277,21 @@ func findModuleRoot(dir string) (string, error) {
 func main() {
 	log.Println("Starting ctxboot code generation tool...")
 
-	if len(os.Args) != 2 {
-		log.Fatal("Usage: generate <package-dir>")
+	graphFormat := flag.String("graph", "", "write the dependency graph as dot, mermaid or json to stdout instead of generating code")
+	flag.Parse()
+	if flag.NArg() != 1 {
+		log.Fatal("Usage: ctxboot [-graph dot|mermaid|json] <package-dir>")
+	}
+	switch *graphFormat {
+	case "", "dot", "mermaid", "json":
+	default:
+		log.Fatalf("Unknown graph format %q, expected dot, mermaid or json", *graphFormat)
 	}
 
-	packageDir := os.Args[1]
+	packageDir := flag.Arg(0)
 	log.Printf("Starting scan from directory: %s", packageDir)
 	components := make([]Component, 0)
+	decorators := make([]Decorator, 0)
 	var packageName string
 
 	// Find module root
@@ -146,6 +312,17 @@ func main() {
 	imports := make(map[string]string) // map[importPath]alias
 	nameCount := make(map[string]int)  // track how many times each name is used
 
+	// Names of the packages the template always imports are taken
+	for _, name := range templateImports {
+		nameCount[name]++
+	}
+
+	// Interfaces and packages found, to report missing dependencies
+	interfaces := make(map[string]bool)
+	typeFiles := make(map[string]string) // files declaring the scanned types, for onMissing
+	types := newTypeMethods()            // methods of the scanned types, for the graph
+	scannedPackages := make(map[string]bool)
+
 	// Create a new token.FileSet to hold all parsed files
 	fset := token.NewFileSet()
 
@@ -182,13 +359,39 @@ func main() {
 			log.Printf("Found package name: %s", packageName)
 		}
 
+		scannedPackages[file.Name.Name] = true
+
 		// Find components in the file
 		componentCount := 0
 		for _, decl := range file.Decls {
+			if funcDecl, ok := decl.(*ast.FuncDecl); ok {
+				types.addMethod(funcDecl, file.Name.Name)
+				if annotation, ok := providerAnnotation(funcDecl.Doc); ok {
+					componentCount++
+					log.Printf("Found provider: %s in file %s", funcDecl.Name.Name, path)
+					components = append(components, providerComponent(funcDecl, annotation, file, path))
+				}
+				if annotation, ok := decoratorAnnotation(funcDecl.Doc); ok {
+					log.Printf("Found decorator: %s in file %s", funcDecl.Name.Name, path)
+					decorators = append(decorators, decoratorFunc(funcDecl, annotation, file, path))
+				}
+				continue
+			}
 			if genDecl, ok := decl.(*ast.GenDecl); ok {
 				for _, spec := range genDecl.Specs {
 					if typeSpec, ok := spec.(*ast.TypeSpec); ok {
-						if hasComponentAnnotation(genDecl.Doc) {
+						typeFiles[qualifiedName(file.Name.Name, typeSpec.Name.Name)] = path
+						if iface, ok := typeSpec.Type.(*ast.InterfaceType); ok {
+							interfaces[qualifiedName(file.Name.Name, typeSpec.Name.Name)] = true
+							types.addInterface(qualifiedName(file.Name.Name, typeSpec.Name.Name), iface)
+						}
+						// Config structs are components bound to properties
+						annotation, ok := componentAnnotation(genDecl.Doc)
+						config, isConfig := configAnnotation(genDecl.Doc)
+						if isConfig {
+							annotation, ok = config, true
+						}
+						if ok {
 							// Check if component is exported
 							if !ast.IsExported(typeSpec.Name.Name) {
 								log.Fatalf("Component %s must be exported (start with capital letter)", typeSpec.Name.Name)
@@ -197,6 +400,15 @@ func main() {
 							componentCount++
 							log.Printf("Found component: %s in file %s", typeSpec.Name.Name, path)
 
+							if scope, ok := annotation["scope"]; ok && scope != "singleton" && scope != "prototype" {
+								log.Fatalf("Component %s has unknown scope %q", typeSpec.Name.Name, scope)
+							}
+							if order, ok := annotation["order"]; ok {
+								if _, err := strconv.Atoi(order); err != nil {
+									log.Fatalf("Component %s has invalid order %q: %v", typeSpec.Name.Name, order, err)
+								}
+							}
+
 							// Get struct type
 							structType, ok := typeSpec.Type.(*ast.StructType)
 							if !ok {
@@ -209,26 +421,18 @@ func main() {
 							for _, field := range structType.Fields.List {
 								if field.Tag != nil {
 									tag := strings.Trim(field.Tag.Value, "`")
-									if strings.Contains(tag, `ctxboot:"inject"`) {
+									if opts, ok := parseInjectTag(tag); ok {
 										// Get field type name
-										switch t := field.Type.(type) {
-										case *ast.StarExpr:
-											switch x := t.X.(type) {
-											case *ast.Ident:
-												deps = append(deps, Dependency{
-													Name:    x.Name,
-													Package: file.Name.Name,
-													File:    path,
-												})
-											case *ast.SelectorExpr:
-												if pkg, ok := x.X.(*ast.Ident); ok {
-													deps = append(deps, Dependency{
-														Name:    x.Sel.Name,
-														Package: pkg.Name,
-														File:    path,
-													})
-												}
+										if dep, ok := fieldDependency(field.Type, file.Name.Name); ok {
+											dep.File = path
+											if len(field.Names) > 0 {
+												dep.Field = field.Names[0].Name
+											} else {
+												dep.Field = dep.Name
 											}
+											dep.Qualifier = opts["name"]
+											dep.Optional = opts["optional"] == "true"
+											deps = append(deps, dep)
 										}
 									}
 								}
@@ -244,6 +448,13 @@ func main() {
 								File:         path,
 								Dependencies: deps,
 								Alias:        imports[filepath.ToSlash(filepath.Join(modulePath, filepath.Dir(path)))],
+								Qualifier:    annotation["name"],
+								Primary:      annotation["primary"] == "true",
+								Order:        annotation["order"],
+								Scope:        annotation["scope"],
+								Config:       isConfig,
+								Prefix:       annotation["prefix"],
+								Conditions:   annotationConditions(annotation),
 							}
 							components = append(components, comp)
 						}
@@ -274,62 +485,106 @@ func main() {
 	sortedComponents := sortByDependencies(components)
 	log.Printf("Sorted components: %v", sortedComponents)
 
-	// Collect unique imports with aliases for same-named packages
-	for _, comp := range components {
-		if comp.Package != "main" {
-			// Get the relative path from module root to the package
-			relPath, err := filepath.Rel(moduleRoot, filepath.Dir(comp.File))
+	reportMissingDependencies(components, interfaces, scannedPackages)
+
+	if *graphFormat != "" {
+		packagePath := func(file string) string {
+			relPath, err := filepath.Rel(moduleRoot, filepath.Dir(file))
 			if err != nil {
 				log.Fatalf("Failed to get relative path: %v", err)
 			}
-			// Skip if the path is the module root itself
-			if relPath != "." {
-				// Normalize the path to use forward slashes
-				normalizedPath := filepath.ToSlash(relPath)
-				// Use the full module path for imports
-				importPath := filepath.ToSlash(filepath.Join(modulePath, normalizedPath))
-
-				// Get the last part of the path as the package name
-				pkgName := filepath.Base(normalizedPath)
-				nameCount[pkgName]++
-
-				// If this name is used more than once, add an alias
-				if nameCount[pkgName] > 1 {
-					imports[importPath] = fmt.Sprintf("%s%d", pkgName, nameCount[pkgName])
-				} else {
-					imports[importPath] = pkgName
-				}
-			}
+			return strings.TrimSuffix(modulePath+"/"+filepath.ToSlash(relPath), "/.")
+		}
+		graph := buildGraph(components, types, typeFiles, packagePath)
+		if err := graph.Write(os.Stdout, *graphFormat); err != nil {
+			log.Fatalf("Failed to write graph: %v", err)
+		}
+		return
+	}
+
+	// addImport imports a package, aliasing it when its name is already taken
+	addImport := func(importPath, pkgName string) {
+		// Packages already imported keep their alias
+		if _, seen := imports[importPath]; seen {
+			return
+		}
+		if _, fixed := templateImports[importPath]; fixed {
+			return
+		}
+		nameCount[pkgName]++
+
+		// If this name is used more than once, add an alias
+		if nameCount[pkgName] > 1 {
+			imports[importPath] = fmt.Sprintf("%s%d", pkgName, nameCount[pkgName])
+		} else {
+			imports[importPath] = pkgName
+		}
+	}
+
+	// addModuleImport imports the module package containing file
+	addModuleImport := func(file string) {
+		// Get the relative path from module root to the package
+		relPath, err := filepath.Rel(moduleRoot, filepath.Dir(file))
+		if err != nil {
+			log.Fatalf("Failed to get relative path: %v", err)
+		}
+		// Skip if the path is the module root itself
+		if relPath != "." {
+			// Normalize the path to use forward slashes
+			normalizedPath := filepath.ToSlash(relPath)
+			// Use the full module path for imports
+			importPath := filepath.ToSlash(filepath.Join(modulePath, normalizedPath))
+
+			// Get the last part of the path as the package name
+			addImport(importPath, filepath.Base(normalizedPath))
+		}
+	}
+
+	// Collect unique imports with aliases for same-named packages
+	for _, comp := range components {
+		if comp.Package != "main" {
+			addModuleImport(comp.File)
 		}
 		for _, dep := range comp.Dependencies {
 			if dep.Package != "main" {
-				// Get the relative path from module root to the package
-				relPath, err := filepath.Rel(moduleRoot, filepath.Dir(dep.File))
-				if err != nil {
-					log.Fatalf("Failed to get relative path: %v", err)
-				}
-				// Skip if the path is the module root itself
-				if relPath != "." {
-					// Normalize the path to use forward slashes
-					normalizedPath := filepath.ToSlash(relPath)
-					// Use the full module path for imports
-					importPath := filepath.ToSlash(filepath.Join(modulePath, normalizedPath))
-
-					// Get the last part of the path as the package name
-					pkgName := filepath.Base(normalizedPath)
-					nameCount[pkgName]++
-
-					// If this name is used more than once, add an alias
-					if nameCount[pkgName] > 1 {
-						imports[importPath] = fmt.Sprintf("%s%d", pkgName, nameCount[pkgName])
-					} else {
-						imports[importPath] = pkgName
-					}
+				addModuleImport(dep.File)
+			}
+		}
+		// onMissing types must be scanned types
+		if comp.OnMissing != "" {
+			file, ok := typeFiles[comp.OnMissing]
+			if !ok {
+				log.Fatalf("Component %s has unknown onMissing type %q", comp.qualifiedName(), comp.OnMissing)
+			}
+			if strings.Contains(comp.OnMissing, ".") {
+				addModuleImport(file)
+			}
+		}
+		// Provided types may come from any package, e.g. *sql.DB
+		if sel, ok := baseType(comp.ResultType).(*ast.SelectorExpr); ok {
+			if pkg, ok := sel.X.(*ast.Ident); ok {
+				if importPath, ok := comp.FileImports[pkg.Name]; ok {
+					addImport(importPath, pkg.Name)
 				}
 			}
 		}
 	}
 
+	for _, d := range decorators {
+		if d.Package != "main" {
+			addModuleImport(d.File)
+		}
+		if d.OnMissing != "" {
+			file, ok := typeFiles[d.OnMissing]
+			if !ok {
+				log.Fatalf("Decorator %s has unknown onMissing type %q", qualifiedName(d.Package, d.Name), d.OnMissing)
+			}
+			if strings.Contains(d.OnMissing, ".") {
+				addModuleImport(file)
+			}
+		}
+	}
+
 	// Convert imports map to slice and sort
 	importsSlice := make([]Import, 0, len(imports))
 	for path, alias := range imports {
@@ -353,16 +608,33 @@ func main() {
 		compPath := filepath.ToSlash(filepath.Join(modulePath, filepath.Dir(comp.File)))
 		alias := imports[compPath]
 
-		info.Components[i] = Component{
-			Name:         comp.Name,
-			Package:      comp.Package,
-			File:         comp.File,
-			Dependencies: comp.Dependencies,
-			Alias:        alias,
+		info.Components[i] = comp
+		info.Components[i].Alias = alias
+		if comp.Provider != "" {
+			info.Components[i].Type = typeString(comp.ResultType, comp, alias, imports)
 		}
+		if comp.OnMissing != "" {
+			info.Components[i].OnMissingType = onMissingType(comp.OnMissing, typeFiles[comp.OnMissing], interfaces[comp.OnMissing], modulePath, imports)
+		}
+	}
+
+	for _, d := range decorators {
+		d.Alias = imports[filepath.ToSlash(filepath.Join(modulePath, filepath.Dir(d.File)))]
+		if d.OnMissing != "" {
+			d.OnMissingType = onMissingType(d.OnMissing, typeFiles[d.OnMissing], interfaces[d.OnMissing], modulePath, imports)
+		}
+		info.Decorators = append(info.Decorators, d)
 	}
 
-	tmpl, err := template.New("registration").Parse(registrationTemplate)
+	// onMissing conditions are evaluated once all other components are registered
+	sort.SliceStable(info.Components, func(i, j int) bool {
+		return info.Components[i].OnMissing == "" && info.Components[j].OnMissing != ""
+	})
+
+	funcs := template.FuncMap{
+		"exportName": exportName,
+	}
+	tmpl, err := template.New("registration").Funcs(funcs).Parse(registrationTemplate)
 	if err != nil {
 		log.Fatalf("Failed to parse template: %v", err)
 	}
@@ -381,73 +653,453 @@ func main() {
 	log.Printf("Successfully generated registration code in %s", outputFile)
 }
 
-func sortByDependencies(components []Component) []Component {
-	// Create dependency graph with fully qualified names
-	graph := make(map[string][]string)
-	nameToComp := make(map[string]Component)
+// reportMissingDependencies warns about required dependencies on scanned
+// types that are neither components nor provided. Interfaces are skipped, as
+// are types of packages that were not scanned: they may be satisfied by
+// implementations or by components registered at runtime
+func reportMissingDependencies(components []Component, interfaces, scannedPackages map[string]bool) {
+	provided := make(map[string]bool)
+	for _, c := range components {
+		provided[c.qualifiedName()] = true
+	}
 
 	for _, c := range components {
-		fullName := c.Name
-		if c.Package != "main" {
-			fullName = c.Package + "." + c.Name
+		for _, dep := range c.Dependencies {
+			name := qualifiedName(dep.Package, dep.Name)
+			if dep.Optional || provided[name] || interfaces[name] || !scannedPackages[dep.Package] {
+				continue
+			}
+			log.Printf("Warning: %s depends on %s, which is not a component; register it before InitializeComponents", c.qualifiedName(), name)
 		}
-		nameToComp[fullName] = c
+	}
+}
 
-		deps := make([]string, len(c.Dependencies))
-		for i, dep := range c.Dependencies {
-			deps[i] = dep.Package + "." + dep.Name
-		}
-		graph[fullName] = deps
+func sortByDependencies(components []Component) []Component {
+	// Create dependency graph, components being identified by type and
+	// qualifier as several may provide the same type
+	byID := make(map[string]Component)
+	byType := make(map[string][]Component)
+	for _, c := range components {
+		byID[componentID(c)] = c
+		byType[c.qualifiedName()] = append(byType[c.qualifiedName()], c)
 	}
 
-	// Perform topological sort
+	// Perform topological sort, recording the path from the component being
+	// visited to report the cycles it closes
 	visited := make(map[string]bool)
 	temp := make(map[string]bool)
 	sorted := make([]Component, 0)
+	var path []string  // components being visited
+	var steps []string // fields or parameters leading from each to the next
+	var cycles []string
 
-	var visit func(name string) bool
-	visit = func(name string) bool {
-		if temp[name] {
-			return false // Cycle detected
-		}
-		if visited[name] {
-			return true
+	var visit func(id string)
+	visit = func(id string) {
+		if visited[id] {
+			return
 		}
-		temp[name] = true
+		temp[id] = true
+		path = append(path, id)
 
-		for _, dep := range graph[name] {
-			if !visit(dep) {
-				return false
+		for _, dep := range byID[id].Dependencies {
+			// Deferred dependencies are resolved on use
+			if dep.Deferred {
+				continue
+			}
+			// Interfaces and types registered at runtime are not in the
+			// graph, and ambiguous dependencies fail at runtime
+			targets, resolved := dependencyTargets(byType[qualifiedName(dep.Package, dep.Name)], dep, true)
+			if !resolved {
+				continue
+			}
+			for _, target := range targets {
+				targetID := componentID(target)
+				steps = append(steps, dependencyStep(byID[id], dep))
+				if temp[targetID] {
+					cycles = append(cycles, cycleChain(path, steps, targetID))
+				} else {
+					visit(targetID)
+				}
+				steps = steps[:len(steps)-1]
 			}
 		}
 
-		delete(temp, name)
-		visited[name] = true
-		sorted = append(sorted, nameToComp[name])
-		return true
+		path = path[:len(path)-1]
+		delete(temp, id)
+		visited[id] = true
+		sorted = append(sorted, byID[id])
 	}
 
 	for _, c := range components {
-		fullName := c.Name
-		if c.Package != "main" {
-			fullName = c.Package + "." + c.Name
+		visit(componentID(c))
+	}
+	if len(cycles) > 0 {
+		log.Fatalf("Cyclic dependency detected:\n\t%s", strings.Join(cycles, "\n\t"))
+	}
+
+	return sorted
+}
+
+// dependencyTargets returns the components among candidates that the runtime
+// injects for dep, selecting them like ctxboot's resolve: the component
+// registered under the requested qualifier, else the only match, else the
+// primary one. exact tells whether the candidates have the requested type
+// rather than implementing it. Collections receive every candidate. When the
+// selection is ambiguous all the matches are returned and resolved is false
+func dependencyTargets(candidates []Component, dep Dependency, exact bool) (targets []Component, resolved bool) {
+	if dep.Collection {
+		return candidates, true
+	}
+	for _, c := range candidates {
+		if exact && c.Qualifier == dep.Qualifier {
+			return []Component{c}, true
 		}
-		if !visit(fullName) {
-			log.Fatalf("Cyclic dependency detected involving component %s", fullName)
+		if dep.Qualifier == "" || c.Qualifier == dep.Qualifier {
+			targets = append(targets, c)
 		}
 	}
+	if len(targets) <= 1 {
+		return targets, true
+	}
 
-	return sorted
+	var primaries []Component
+	for _, c := range targets {
+		if c.Primary {
+			primaries = append(primaries, c)
+		}
+	}
+	if len(primaries) == 1 {
+		return primaries, true
+	}
+	return targets, false
+}
+
+// dependencyStep names the field or provider parameter through which c
+// depends on dep, e.g. repository.UserRepository.db or database.NewPool#0
+func dependencyStep(c Component, dep Dependency) string {
+	if c.Provider != "" {
+		return qualifiedName(c.Package, c.Provider) + dep.Field
+	}
+	return c.qualifiedName() + "." + dep.Field
+}
+
+// cycleChain formats the cycle closed by a dependency on name, the last of
+// steps, as the steps from name back to it, e.g. A.b -> B.c -> C.a -> A
+func cycleChain(path, steps []string, name string) string {
+	for i, visiting := range path {
+		if visiting == name {
+			return strings.Join(append(append([]string(nil), steps[i:]...), name), " -> ")
+		}
+	}
+	return name
+}
+
+// qualifiedName returns the name of a type as written in the generated code
+// of package main
+func qualifiedName(pkg, name string) string {
+	if pkg == "main" {
+		return name
+	}
+	return pkg + "." + name
+}
+
+// qualifiedName returns the name of the component's type as used in the
+// dependency graph
+func (c Component) qualifiedName() string {
+	if c.Provider != "" {
+		return qualifiedName(c.ResultPackage, c.Name)
+	}
+	return qualifiedName(c.Package, c.Name)
+}
+
+// providerComponent describes the component created by a //ctxboot:provider
+// function. Its parameters are the component's dependencies
+func providerComponent(funcDecl *ast.FuncDecl, annotation map[string]string, file *ast.File, path string) Component {
+	name := funcDecl.Name.Name
+	if funcDecl.Recv != nil {
+		log.Fatalf("Provider %s must be a function, not a method", name)
+	}
+	if !ast.IsExported(name) {
+		log.Fatalf("Provider %s must be exported (start with capital letter)", name)
+	}
+	results := funcDecl.Type.Results
+	if results == nil || results.NumFields() == 0 || results.NumFields() > 2 {
+		log.Fatalf("Provider %s must return a component, optionally followed by an error", name)
+	}
+
+	// Get the provided type
+	resultType := results.List[0].Type
+	result, ok := fieldDependency(resultType, file.Name.Name)
+	if !ok || result.Collection || result.Deferred {
+		log.Fatalf("Provider %s returns an unsupported type", name)
+	}
+
+	// Get dependencies
+	deps := make([]Dependency, 0)
+	index := 0 // parameter index, as named by the runtime
+	for _, param := range funcDecl.Type.Params.List {
+		count := max(len(param.Names), 1)
+		dep, ok := fieldDependency(param.Type, file.Name.Name)
+		if !ok || dep.Deferred {
+			index += count
+			continue
+		}
+		dep.File = path
+		for i := 0; i < count; i++ {
+			dep.Field = fmt.Sprintf("#%d", index)
+			deps = append(deps, dep)
+			index++
+		}
+	}
+	if len(deps) > 0 {
+		log.Printf("Provider %s has dependencies: %v", name, deps)
+	}
+
+	fileImports := make(map[string]string)
+	for _, imp := range file.Imports {
+		importPath := strings.Trim(imp.Path.Value, `"`)
+		importName := filepath.Base(importPath)
+		if imp.Name != nil {
+			importName = imp.Name.Name
+		}
+		fileImports[importName] = importPath
+	}
+
+	return Component{
+		Name:          result.Name,
+		Package:       file.Name.Name,
+		File:          path,
+		Dependencies:  deps,
+		Qualifier:     annotation["name"],
+		Primary:       annotation["primary"] == "true",
+		Order:         annotation["order"],
+		Provider:      name,
+		ResultType:    resultType,
+		ResultPackage: result.Package,
+		FileImports:   fileImports,
+		Conditions:    annotationConditions(annotation),
+	}
+}
+
+// decoratorFunc describes a //ctxboot:decorator function, which must take
+// and return the decorated interface, optionally followed by an error
+func decoratorFunc(funcDecl *ast.FuncDecl, annotation map[string]string, file *ast.File, path string) Decorator {
+	name := funcDecl.Name.Name
+	if funcDecl.Recv != nil {
+		log.Fatalf("Decorator %s must be a function, not a method", name)
+	}
+	if !ast.IsExported(name) {
+		log.Fatalf("Decorator %s must be exported (start with capital letter)", name)
+	}
+	params, results := funcDecl.Type.Params, funcDecl.Type.Results
+	if params.NumFields() != 1 || results == nil || results.NumFields() == 0 || results.NumFields() > 2 {
+		log.Fatalf("Decorator %s must take and return the decorated component, optionally followed by an error", name)
+	}
+	if order, ok := annotation["order"]; ok {
+		if _, err := strconv.Atoi(order); err != nil {
+			log.Fatalf("Decorator %s has invalid order %q: %v", name, order, err)
+		}
+	}
+
+	return Decorator{
+		Name:       name,
+		Package:    file.Name.Name,
+		File:       path,
+		Order:      annotation["order"],
+		Conditions: annotationConditions(annotation),
+	}
+}
+
+// baseType strips pointers from a type expression
+func baseType(expr ast.Expr) ast.Expr {
+	for {
+		star, ok := expr.(*ast.StarExpr)
+		if !ok {
+			return expr
+		}
+		expr = star.X
+	}
 }
 
-func hasComponentAnnotation(doc *ast.CommentGroup) bool {
+// typeString renders the type provided by comp for the generated file, alias
+// being the import alias of the provider's package
+func typeString(expr ast.Expr, comp Component, alias string, imports map[string]string) string {
+	switch t := expr.(type) {
+	case *ast.StarExpr:
+		return "*" + typeString(t.X, comp, alias, imports)
+	case *ast.Ident:
+		if comp.Package == "main" || !ast.IsExported(t.Name) {
+			return t.Name
+		}
+		if alias == "" {
+			alias = comp.Package
+		}
+		return alias + "." + t.Name
+	case *ast.SelectorExpr:
+		pkg := t.X.(*ast.Ident).Name
+		if importAlias, ok := imports[comp.FileImports[pkg]]; ok {
+			pkg = importAlias
+		}
+		return pkg + "." + t.Sel.Name
+	}
+	log.Fatalf("Provider %s returns an unsupported type", comp.Provider)
+	return ""
+}
+
+// fieldDependency returns the type an inject field of type expr depends on.
+// Pointers are dereferenced, for slices and maps the element type is returned
+// as a collection dependency, and for ctxboot.Lazy and ctxboot.Provider the
+// type argument as a deferred dependency. pkg is the package declaring the field
+func fieldDependency(expr ast.Expr, pkg string) (Dependency, bool) {
+	switch t := expr.(type) {
+	case *ast.StarExpr:
+		return fieldDependency(t.X, pkg)
+	case *ast.ArrayType:
+		dep, ok := fieldDependency(t.Elt, pkg)
+		dep.Collection = true
+		return dep, ok
+	case *ast.MapType:
+		dep, ok := fieldDependency(t.Value, pkg)
+		dep.Collection = true
+		return dep, ok
+	case *ast.Ident:
+		return Dependency{Name: t.Name, Package: pkg}, true
+	case *ast.SelectorExpr:
+		if x, ok := t.X.(*ast.Ident); ok {
+			// ctxboot.Publisher is the event bus of the context
+			if x.Name == "ctxboot" && t.Sel.Name == "Publisher" {
+				return Dependency{}, false
+			}
+			return Dependency{Name: t.Sel.Name, Package: x.Name}, true
+		}
+	case *ast.IndexExpr:
+		// ctxboot.Lazy[T] and ctxboot.Provider[T] depend on T once used
+		if x, ok := t.X.(*ast.SelectorExpr); ok {
+			if ident, ok := x.X.(*ast.Ident); ok && ident.Name == "ctxboot" && (x.Sel.Name == "Lazy" || x.Sel.Name == "Provider") {
+				dep, ok := fieldDependency(t.Index, pkg)
+				dep.Deferred = true
+				return dep, ok
+			}
+		}
+	}
+	return Dependency{}, false
+}
+
+// componentAnnotation looks for a //ctxboot:component line in doc and returns
+// its options, e.g. "//ctxboot:component name=replica" yields {"name": "replica"}.
+// Options without a value map to "true"
+func componentAnnotation(doc *ast.CommentGroup) (map[string]string, bool) {
+	return annotation(doc, "//ctxboot:component")
+}
+
+// configAnnotation looks for a //ctxboot:config line in doc and returns its
+// options, e.g. "//ctxboot:config prefix=db" yields {"prefix": "db"}
+func configAnnotation(doc *ast.CommentGroup) (map[string]string, bool) {
+	return annotation(doc, "//ctxboot:config")
+}
+
+// onMissingType returns the reflect.Type expression of the scanned type named
+// by an onMissing condition, declared in file. Interfaces are looked up as
+// such and structs as pointers, like components
+func onMissingType(name, file string, isInterface bool, modulePath string, imports map[string]string) string {
+	typ := name
+	if pkg, typeName, ok := strings.Cut(name, "."); ok {
+		if alias := imports[filepath.ToSlash(filepath.Join(modulePath, filepath.Dir(file)))]; alias != "" {
+			pkg = alias
+		}
+		typ = pkg + "." + typeName
+	}
+	if isInterface {
+		return fmt.Sprintf("reflect.TypeOf((*%s)(nil)).Elem()", typ)
+	}
+	return fmt.Sprintf("reflect.TypeOf((*%s)(nil))", typ)
+}
+
+// annotationConditions returns the registration conditions of a component
+// annotation, e.g. "//ctxboot:component profile=dev onMissing=database.Database"
+func annotationConditions(annotation map[string]string) Conditions {
+	return Conditions{
+		Profile:    annotation["profile"],
+		OnEnv:      annotation["onEnv"],
+		OnProperty: annotation["onProperty"],
+		OnMissing:  annotation["onMissing"],
+	}
+}
+
+// providerAnnotation looks for a //ctxboot:provider line in doc and returns
+// its options
+func providerAnnotation(doc *ast.CommentGroup) (map[string]string, bool) {
+	return annotation(doc, "//ctxboot:provider")
+}
+
+// decoratorAnnotation looks for a //ctxboot:decorator line in doc and
+// returns its options, e.g. "//ctxboot:decorator order=1"
+func decoratorAnnotation(doc *ast.CommentGroup) (map[string]string, bool) {
+	return annotation(doc, "//ctxboot:decorator")
+}
+
+// annotation looks for a line starting with directive in doc and parses the
+// space separated key=value options following it
+func annotation(doc *ast.CommentGroup, directive string) (map[string]string, bool) {
 	if doc == nil {
-		return false
+		return nil, false
 	}
 	for _, comment := range doc.List {
-		if strings.Contains(comment.Text, "//ctxboot:component") {
-			return true
+		rest, ok := strings.CutPrefix(comment.Text, directive)
+		if !ok || (rest != "" && rest[0] != ' ' && rest[0] != '\t') {
+			continue
+		}
+		opts := make(map[string]string)
+		for _, field := range strings.Fields(rest) {
+			key, value, found := strings.Cut(field, "=")
+			if !found {
+				value = "true"
+			}
+			opts[key] = value
+		}
+		return opts, true
+	}
+	return nil, false
+}
+
+// parseInjectTag parses the ctxboot struct tag of a field and returns its
+// options, e.g. `ctxboot:"inject,name=replica"` yields {"name": "replica"}.
+// The second return value reports whether the field is an injection point
+func parseInjectTag(tag string) (map[string]string, bool) {
+	value, ok := reflect.StructTag(tag).Lookup("ctxboot")
+	if !ok {
+		return nil, false
+	}
+	parts := strings.Split(value, ",")
+	if strings.TrimSpace(parts[0]) != "inject" {
+		return nil, false
+	}
+	opts := make(map[string]string)
+	for _, part := range parts[1:] {
+		key, val, found := strings.Cut(strings.TrimSpace(part), "=")
+		if !found {
+			val = "true"
+		}
+		opts[key] = val
+	}
+	return opts, true
+}
+
+// exportName turns a qualifier such as "read-replica" into an exported
+// identifier fragment such as "ReadReplica"
+func exportName(name string) string {
+	var b strings.Builder
+	upper := true
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
 		}
+		b.WriteRune(r)
 	}
-	return false
+	return b.String()
 }
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"reflect"
+	"testing"
+)
+
+// parseProviders returns the //ctxboot:provider functions of src
+func parseProviders(t *testing.T, src string) []Component {
+	t.Helper()
+	file, err := parser.ParseFile(token.NewFileSet(), "store/store.go", src, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var components []Component
+	for _, decl := range file.Decls {
+		if funcDecl, ok := decl.(*ast.FuncDecl); ok {
+			if annotation, ok := providerAnnotation(funcDecl.Doc); ok {
+				components = append(components, providerComponent(funcDecl, annotation, file, "store/store.go"))
+			}
+		}
+	}
+	return components
+}
+
+func componentIDs(components []Component) []string {
+	ids := make([]string, len(components))
+	for i, c := range components {
+		ids[i] = componentID(c)
+	}
+	return ids
+}
+
+func TestSortByDependenciesNamedProviders(t *testing.T) {
+	components := parseProviders(t, `package store
+
+import "database/sql"
+
+//ctxboot:provider name=replica
+func NewReplica(p *sql.DB) *sql.DB { return p }
+
+//ctxboot:provider name=primary primary
+func NewPrimary() *sql.DB { return nil }
+
+//ctxboot:provider
+func NewCache(replica *sql.DB) *Cache { return nil }
+`)
+	// The unqualified parameters resolve to the primary
+	sorted := sortByDependencies(components)
+	want := []string{"sql.DB(primary)", "sql.DB(replica)", "store.Cache"}
+	if got := componentIDs(sorted); !reflect.DeepEqual(got, want) {
+		t.Errorf("sortByDependencies() = %v, want %v", got, want)
+	}
+}
+
+func TestDependencyTargets(t *testing.T) {
+	unnamed := Component{Name: "Pool", Package: "db"}
+	primary := Component{Name: "Pool", Package: "db", Qualifier: "primary", Primary: true}
+	replica := Component{Name: "Pool", Package: "db", Qualifier: "replica"}
+
+	tests := []struct {
+		name         string
+		candidates   []Component
+		dep          Dependency
+		exact        bool
+		want         []string
+		wantResolved bool
+	}{
+		{"exact qualifier", []Component{primary, replica}, Dependency{Qualifier: "replica"}, true, []string{"db.Pool(replica)"}, true},
+		{"unknown qualifier", []Component{primary, replica}, Dependency{Qualifier: "other"}, true, []string{}, true},
+		{"unnamed first", []Component{primary, unnamed}, Dependency{}, true, []string{"db.Pool"}, true},
+		{"primary", []Component{replica, primary}, Dependency{}, true, []string{"db.Pool(primary)"}, true},
+		{"ambiguous", []Component{replica, replica}, Dependency{}, true, []string{"db.Pool(replica)", "db.Pool(replica)"}, false},
+		{"collection", []Component{primary, replica}, Dependency{Qualifier: "replica", Collection: true}, true, []string{"db.Pool(primary)", "db.Pool(replica)"}, true},
+		{"implementations", []Component{unnamed, replica}, Dependency{}, false, []string{"db.Pool", "db.Pool(replica)"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			targets, resolved := dependencyTargets(tt.candidates, tt.dep, tt.exact)
+			if got := componentIDs(targets); !reflect.DeepEqual(got, tt.want) || resolved != tt.wantResolved {
+				t.Errorf("dependencyTargets() = %v, %v, want %v, %v", got, resolved, tt.want, tt.wantResolved)
+			}
+		})
+	}
+}
+
+func TestFieldDependency(t *testing.T) {
+	tests := []struct {
+		expr   string
+		want   Dependency
+		wantOK bool
+	}{
+		{"*Config", Dependency{Name: "Config", Package: "app"}, true},
+		{"database.Database", Dependency{Name: "Database", Package: "database"}, true},
+		{"[]Handler", Dependency{Name: "Handler", Package: "app", Collection: true}, true},
+		{"map[string]*Handler", Dependency{Name: "Handler", Package: "app", Collection: true}, true},
+		{"ctxboot.Lazy[*Config]", Dependency{Name: "Config", Package: "app", Deferred: true}, true},
+		{"ctxboot.Provider[[]db.Pool]", Dependency{Name: "Pool", Package: "db", Collection: true, Deferred: true}, true},
+		{"ctxboot.Publisher", Dependency{}, false},
+		{"other.Lazy[*Config]", Dependency{}, false},
+		{"func()", Dependency{}, false},
+	}
+	for _, tt := range tests {
+		expr, err := parser.ParseExpr(tt.expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, ok := fieldDependency(expr, "app"); got != tt.want || ok != tt.wantOK {
+			t.Errorf("fieldDependency(%s) = %+v, %v, want %+v, %v", tt.expr, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package ctxboot
+
+import (
+	"reflect"
+	"sort"
+)
+
+// SetOrder sets the position of the component registered for typ within
+// injected slices. Components are sorted by ascending order, components with
+// the same order keeping their registration order. The default order is 0
+func (c *CtxbootComponentContext) SetOrder(typ reflect.Type, order int) error {
+	return c.SetNamedOrder("", typ, order)
+}
+
+// SetNamedOrder is like SetOrder for a component registered under a qualifier
+// name
+func (c *CtxbootComponentContext) SetNamedOrder(name string, typ reflect.Type, order int) error {
+	key := componentKey{typ: typ, name: name}
+
+	c.mu.Lock()
+	reinject, err := c.checkRegistration(key)
+	if err != nil {
+		c.mu.Unlock()
+		return err
+	}
+	if !c.registered(key) {
+		c.mu.Unlock()
+		return newError(ErrComponentNotFound, key, "cannot set order")
+	}
+	c.orders[key] = order
+	c.mu.Unlock()
+
+	if reinject {
+		return c.refreshDependents(key)
+	}
+	return nil
+}
+
+// collectionElem reports whether a field of type fieldType receives a
+// collection of components, []T or map[string]T, and returns T
+func collectionElem(fieldType reflect.Type) (reflect.Type, bool) {
+	switch fieldType.Kind() {
+	case reflect.Slice:
+		return fieldType.Elem(), true
+	case reflect.Map:
+		if fieldType.Key().Kind() == reflect.String {
+			return fieldType.Elem(), true
+		}
+	}
+	return nil, false
+}
+
+// collectionKeys returns every component registered under typ or, for an
+// interface, implementing it, sorted by order. Caller must hold c.mu
+func (c *CtxbootComponentContext) collectionKeys(typ reflect.Type) []componentKey {
+	var keys []componentKey
+	for _, key := range c.order {
+		if key.satisfies(typ, "") {
+			keys = append(keys, key)
+		}
+	}
+	sort.SliceStable(keys, func(i, j int) bool {
+		return c.orders[keys[i]] < c.orders[keys[j]]
+	})
+	return keys
+}
+
+// collectionEntry is a component contributing to an injected collection
+type collectionEntry struct {
+	key   componentKey
+	owner *CtxbootComponentContext // context the component is registered in
+	order int
+}
+
+// collectionEntries returns the components of c and its ancestors matching
+// typ, sorted by order. Components overridden in a child context are skipped
+func (c *CtxbootComponentContext) collectionEntries(typ reflect.Type) []collectionEntry {
+	var entries []collectionEntry
+	seen := make(map[componentKey]bool)
+	for ctx := c; ctx != nil; ctx = ctx.parent {
+		ctx.mu.RLock()
+		for _, key := range ctx.collectionKeys(typ) {
+			if !seen[key] {
+				seen[key] = true
+				entries = append(entries, collectionEntry{key: key, owner: ctx, order: ctx.orders[key]})
+			}
+		}
+		ctx.mu.RUnlock()
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].order < entries[j].order
+	})
+	return entries
+}
+
+// collectionValue builds the slice or map to inject into a collection field.
+// Map entries are keyed by component name, unnamed components by their type
+func (c *CtxbootComponentContext) collectionValue(fieldType reflect.Type) (reflect.Value, error) {
+	elemType := fieldType.Elem()
+	entries := c.collectionEntries(lookupType(elemType))
+
+	var collection reflect.Value
+	if fieldType.Kind() == reflect.Slice {
+		collection = reflect.MakeSlice(fieldType, 0, len(entries))
+	} else {
+		collection = reflect.MakeMapWithSize(fieldType, len(entries))
+	}
+
+	for _, entry := range entries {
+		key := entry.key
+		component, err := entry.owner.instance(key)
+		if err == nil {
+			component, err = entry.owner.decorate(key, lookupType(elemType), component)
+		}
+		if err != nil {
+			return reflect.Value{}, err
+		}
+
+		compVal := reflect.ValueOf(component)
+		if elemType.Kind() != reflect.Ptr && elemType.Kind() != reflect.Interface {
+			// If elements are not pointers, dereference the component
+			compVal = compVal.Elem()
+		}
+
+		if fieldType.Kind() == reflect.Slice {
+			collection = reflect.Append(collection, compVal)
+			continue
+		}
+
+		name := key.name
+		if name == "" {
+			name = key.typ.String()
+		}
+		mapKey := reflect.ValueOf(name).Convert(fieldType.Key())
+		if collection.MapIndex(mapKey).IsValid() {
+			return reflect.Value{}, newError(ErrAmbiguousComponent, componentKey{typ: fieldType}, "duplicate key %q", name)
+		}
+		collection.SetMapIndex(mapKey, compVal)
+	}
+	return collection, nil
+}
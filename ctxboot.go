@@ -1,74 +1,277 @@
 package ctxboot
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"reflect"
 	"sync"
+	"time"
 	"unsafe"
 )
 
 // CtxbootComponentContext manages components and their dependencies
 type CtxbootComponentContext struct {
-	components map[reflect.Type]interface{}
-	mu         sync.RWMutex
+	components        map[componentKey]interface{}
+	order             []componentKey                      // registration order
+	initOrder         []componentKey                      // order used by the last InitializeComponents
+	providers         map[componentKey]reflect.Value      // provider functions, see SetProvider
+	prototypes        map[componentKey]func() interface{} // prototype constructors, see SetPrototype
+	primary           map[componentKey]bool
+	orders            map[componentKey]int           // position within injected collections
+	configs           map[componentKey]string        // property prefixes of config components
+	props             *Properties                    // see SetProperties
+	profiles          []string                       // see SetProfiles
+	state             State                          // see State
+	unsafeReinjection bool                           // see EnableUnsafeReinjection
+	initWorkers       int                            // see SetInitWorkers
+	observers         []Observer                     // see AddObserver
+	decorators        []decorator                    // see AddDecorator
+	decorated         map[decoratedKey]decoration    // see decorate
+	decorating        map[decoratedKey]chan struct{} // decorations in progress, see decorate
+	events            *eventBus                      // see Publisher
+	healthTimeout     time.Duration                  // see SetHealthCheckTimeout
+	inits             map[componentKey]InitTiming    // last initialization of each singleton
+	parent            *CtxbootComponentContext       // consulted for components not registered here
+	mu                sync.RWMutex
+}
+
+// componentKey identifies a registered component by its type and qualifier
+// name. Components registered without a name use the empty name
+type componentKey struct {
+	typ  reflect.Type
+	name string
+}
+
+// String returns the type of the component followed by its name, if any
+func (k componentKey) String() string {
+	if k.name == "" {
+		return k.typ.String()
+	}
+	return fmt.Sprintf("%v(%s)", k.typ, k.name)
+}
+
+// satisfies reports whether the component registered under k satisfies typ
+// and, if not empty, name: its type is typ or, for an interface, implements
+// it
+func (k componentKey) satisfies(typ reflect.Type, name string) bool {
+	if name != "" && k.name != name {
+		return false
+	}
+	return k.typ == typ || (typ.Kind() == reflect.Interface && k.typ.Implements(typ))
 }
 
 // NewCtxbootComponentContext creates a new component context
 func NewCtxbootComponentContext() *CtxbootComponentContext {
 	return &CtxbootComponentContext{
-		components: make(map[reflect.Type]interface{}),
+		components: make(map[componentKey]interface{}),
+		providers:  make(map[componentKey]reflect.Value),
+		prototypes: make(map[componentKey]func() interface{}),
+		primary:    make(map[componentKey]bool),
+		orders:     make(map[componentKey]int),
+		configs:    make(map[componentKey]string),
+		events:     newEventBus(nil),
 	}
 }
 
+// NewChild creates a child context. Components registered in the child
+// override those of c, and lookups and injections fall back to c, and so on
+// up the chain, for components the child does not register
+func (c *CtxbootComponentContext) NewChild() *CtxbootComponentContext {
+	child := NewCtxbootComponentContext()
+	child.parent = c
+	child.events = newEventBus(c.events)
+	return child
+}
+
 // GetComponent retrieves a component by its type
 func (c *CtxbootComponentContext) GetComponent(typ reflect.Type) (interface{}, error) {
+	return c.GetNamedComponent("", typ)
+}
+
+// GetNamedComponent retrieves a component by its qualifier name and type.
+// An empty name behaves like GetComponent
+func (c *CtxbootComponentContext) GetNamedComponent(name string, typ reflect.Type) (interface{}, error) {
 	c.mu.RLock()
-	defer c.mu.RUnlock()
+	found := len(c.candidates(typ, name)) > 0
+	key, err := c.resolve(typ, name)
+	c.mu.RUnlock()
 
-	// First try exact match
-	if component, ok := c.components[typ]; ok {
-		return component, nil
+	// The event bus is injected unless a Publisher is registered
+	if !found && typ == publisherType && name == "" {
+		return c.events, nil
 	}
 
-	// If the requested type is an interface, look for implementations
-	if typ.Kind() == reflect.Interface {
-		var candidates []reflect.Type
-		for t := range c.components {
-			// Check if the component type implements the interface
-			if t.Implements(typ) {
-				candidates = append(candidates, t)
-			}
-		}
+	// Fall back to the parent context for components not registered here
+	if !found && c.parent != nil {
+		return c.parent.GetNamedComponent(name, typ)
+	}
+	if err != nil {
+		return nil, err
+	}
 
-		// If no candidates found, return error
-		if len(candidates) == 0 {
-			return nil, fmt.Errorf("no component found that implements interface: %v", typ)
-		}
+	component, err := c.instance(key)
+	if err != nil {
+		return nil, err
+	}
+	return c.decorate(key, typ, component)
+}
+
+// instance returns the component registered under key, creating a new one
+// for prototype components
+func (c *CtxbootComponentContext) instance(key componentKey) (interface{}, error) {
+	c.mu.RLock()
+	component, ok := c.components[key]
+	constructor, isPrototype := c.prototypes[key]
+	c.mu.RUnlock()
+
+	if isPrototype {
+		return c.newPrototype(key, constructor)
+	}
+	if !ok {
+		return nil, newError(ErrComponentNotFound, key, "created by its provider during InitializeComponents")
+	}
+	return component, nil
+}
+
+// MarkPrimary marks the component registered for typ as the one to use when
+// several registered components satisfy a requested interface or type
+func (c *CtxbootComponentContext) MarkPrimary(typ reflect.Type) error {
+	return c.MarkNamedPrimary("", typ)
+}
+
+// MarkNamedPrimary is like MarkPrimary for a component registered under a
+// qualifier name
+func (c *CtxbootComponentContext) MarkNamedPrimary(name string, typ reflect.Type) error {
+	key := componentKey{typ: typ, name: name}
+
+	c.mu.Lock()
+	reinject, err := c.checkRegistration(key)
+	if err != nil {
+		c.mu.Unlock()
+		return err
+	}
+	if !c.registered(key) {
+		c.mu.Unlock()
+		return newError(ErrComponentNotFound, key, "cannot mark as primary")
+	}
+	c.primary[key] = true
+	c.mu.Unlock()
+
+	if reinject {
+		return c.refreshDependents(key)
+	}
+	return nil
+}
+
+// resolve finds the key of the single component satisfying typ and name,
+// preferring a primary component when several match. Caller must hold c.mu
+func (c *CtxbootComponentContext) resolve(typ reflect.Type, name string) (componentKey, error) {
+	candidates := c.candidates(typ, name)
+
+	// If no candidates found, return error
+	requested := componentKey{typ: typ, name: name}
+	if len(candidates) == 0 {
+		return componentKey{}, newError(ErrComponentNotFound, requested, "")
+	}
 
-		// If multiple candidates found, panic
-		if len(candidates) > 1 {
-			panic(fmt.Sprintf("multiple components implement interface %v: %v", typ, candidates))
+	// Return the single candidate
+	if len(candidates) == 1 {
+		return candidates[0], nil
+	}
+
+	// If multiple candidates found, fall back to the primary one
+	var primaries []componentKey
+	for _, key := range candidates {
+		if c.primary[key] {
+			primaries = append(primaries, key)
 		}
+	}
+	switch len(primaries) {
+	case 1:
+		return primaries[0], nil
+	case 0:
+		return componentKey{}, newError(ErrAmbiguousComponent, requested, "candidates %v, none is primary", candidates)
+	default:
+		return componentKey{}, newError(ErrAmbiguousComponent, requested, "several primary candidates %v", primaries)
+	}
+}
 
-		// Return the single candidate
-		return c.components[candidates[0]], nil
+// candidates returns the keys of the components satisfying typ, in
+// registration order. A non-empty name restricts the candidates to components
+// registered under that name. Caller must hold c.mu
+func (c *CtxbootComponentContext) candidates(typ reflect.Type, name string) []componentKey {
+	// First try exact match
+	exact := componentKey{typ: typ, name: name}
+	if c.registered(exact) {
+		return []componentKey{exact}
 	}
 
-	return nil, fmt.Errorf("component not found: %v", typ)
+	var candidates []componentKey
+	for _, key := range c.order {
+		if key.satisfies(typ, name) {
+			candidates = append(candidates, key)
+		}
+	}
+	return candidates
+}
+
+// registered reports whether a component or a provider is registered under
+// key. Caller must hold c.mu
+func (c *CtxbootComponentContext) registered(key componentKey) bool {
+	if _, ok := c.components[key]; ok {
+		return true
+	}
+	if _, ok := c.providers[key]; ok {
+		return true
+	}
+	_, ok := c.prototypes[key]
+	return ok
 }
 
 // SetComponent stores a component instance
 func (c *CtxbootComponentContext) SetComponent(typ reflect.Type, instance interface{}) error {
+	return c.SetNamedComponent("", typ, instance)
+}
+
+// SetNamedComponent stores a component instance under a qualifier name, so
+// that several components of the same type can live in one context
+func (c *CtxbootComponentContext) SetNamedComponent(name string, typ reflect.Type, instance interface{}) error {
 	if instance == nil {
 		return errors.New("cannot store nil component")
 	}
 
+	key := componentKey{typ: typ, name: name}
+	instance, err := assignableInstance(key, instance)
+	if err != nil {
+		return err
+	}
+
+	// Store the component (overwriting if it exists)
+	c.mu.Lock()
+	reinject, err := c.checkRegistration(key)
+	if err != nil {
+		c.mu.Unlock()
+		return err
+	}
+	c.storeComponent(key, instance)
+	c.mu.Unlock()
+
+	c.notify(func(o Observer) { o.OnRegister(key.id()) })
+	if reinject {
+		return c.reinject(key)
+	}
+	return nil
+}
+
+// assignableInstance returns instance as a component to be registered under
+// key, taking its address if key is a pointer type and instance is not
+func assignableInstance(key componentKey, instance interface{}) (interface{}, error) {
 	// Get the actual type of the instance
 	instanceType := reflect.TypeOf(instance)
 
 	// If typ is a pointer type but instance is not, create a pointer to instance
-	if typ.Kind() == reflect.Ptr && instanceType.Kind() != reflect.Ptr {
+	if key.typ.Kind() == reflect.Ptr && instanceType.Kind() != reflect.Ptr {
 		// Create a new pointer to the instance
 		ptr := reflect.New(instanceType)
 		ptr.Elem().Set(reflect.ValueOf(instance))
@@ -76,93 +279,309 @@ func (c *CtxbootComponentContext) SetComponent(typ reflect.Type, instance interf
 		instanceType = reflect.TypeOf(instance)
 	}
 
-	if !instanceType.AssignableTo(typ) {
-		return fmt.Errorf("instance type %v is not assignable to %v", instanceType, typ)
+	if !instanceType.AssignableTo(key.typ) {
+		return nil, newError(ErrNotAssignable, key, "instance type %v", instanceType)
 	}
+	return instance, nil
+}
 
-	// Store the component (overwriting if it exists)
-	c.mu.Lock()
-	c.components[typ] = instance
-	c.mu.Unlock()
+// storeComponent registers instance under key, replacing any component,
+// provider or prototype registered under it. Caller must hold c.mu
+func (c *CtxbootComponentContext) storeComponent(key componentKey, instance interface{}) {
+	if !c.registered(key) {
+		c.order = append(c.order, key)
+	}
+	c.components[key] = instance
+	delete(c.providers, key)
+	delete(c.prototypes, key)
+	c.forgetInitialized(key)
+}
 
-	return nil
+// forgetInitialized drops key from the components initialized by a failed
+// InitializeComponents when it is replaced, for the next one to initialize
+// the replacement. The replaced instance is not stopped. Caller must hold c.mu
+func (c *CtxbootComponentContext) forgetInitialized(key componentKey) {
+	if c.state == StateRegistering {
+		c.initOrder = withoutKey(c.initOrder, key)
+	}
 }
 
-// InitializeComponents injects dependencies into all registered components
-func (c *CtxbootComponentContext) InitializeComponents() error {
-	// Create a copy of components to avoid concurrent modification
-	components := make(map[reflect.Type]interface{})
+// InitializeComponents creates the components of registered providers,
+// injects dependencies into all registered components and runs their Init
+// hooks, a component being initialized only after all of its dependencies are.
+// On success the context is sealed, see State
+func (c *CtxbootComponentContext) InitializeComponents() (err error) {
+	// Create a copy of the registration order to avoid concurrent modification
+	c.mu.Lock()
+	if c.state != StateRegistering {
+		defer c.mu.Unlock()
+		return &Error{Err: ErrContextSealed, Detail: fmt.Sprintf("context is %v", c.state)}
+	}
+	c.state = StateInitializing
+	order := append([]componentKey(nil), c.order...)
+	workers := c.initWorkers
+	c.mu.Unlock()
+
+	// Components initialized by a failed attempt are resumed rather than
+	// initialized again. Those of providers that failed to be created again
+	// are not initialized
+	resumed := make(map[componentKey]bool)
+	initOrder := make([]componentKey, 0, len(order))
 	c.mu.RLock()
-	for typ, comp := range c.components {
-		components[typ] = comp
+	for _, key := range c.initOrder {
+		_, isProvided := c.providers[key]
+		if _, exists := c.components[key]; exists || !isProvided {
+			resumed[key] = true
+			initOrder = append(initOrder, key)
+		}
 	}
 	c.mu.RUnlock()
 
-	// Track initialized components
-	initialized := make(map[reflect.Type]bool)
+	// Remember what was initialized, even on failure, so Shutdown can tear it
+	// down, and seal the context on success
+	defer func() {
+		c.mu.Lock()
+		c.initOrder = initOrder
+		c.state = StateInitialized
+		if err != nil {
+			c.state = StateRegistering
+		}
+		c.mu.Unlock()
+
+		// Listeners see the sealed context, and cannot unseal it
+		if err == nil {
+			if perr := c.events.publish(context.Background(), ContextInitialized{}, false); perr != nil {
+				c.events.handleError(ContextInitialized{}, perr)
+			}
+		}
+	}()
+
+	if workers > 1 {
+		return c.initializeConcurrently(order, workers, resumed, &initOrder)
+	}
+
+	// Track initialized components, and those initialized with a new
+	// instance by this attempt
+	initialized := make(map[componentKey]bool)
+	fresh := make(map[componentKey]bool)
 
 	// Initialize components until all are done or we can't make progress
-	for len(initialized) < len(components) {
+	for len(initialized) < len(order) {
 		progress := false
 
-		for typ, instance := range components {
-			if initialized[typ] {
+		for _, key := range order {
+			if initialized[key] {
 				continue
 			}
 
 			// Check if all dependencies are initialized
-			val := reflect.ValueOf(instance)
-			if val.Kind() != reflect.Ptr {
-				return fmt.Errorf("component must be a pointer: %v", typ)
-			}
-
-			elem := val.Elem()
-			if elem.Kind() != reflect.Struct {
-				return fmt.Errorf("component must be a pointer to a struct: %v", typ)
+			deps, err := c.componentDependencies(key)
+			if err != nil {
+				return err
 			}
 
 			allDepsInitialized := true
-			for i := 0; i < elem.Type().NumField(); i++ {
-				field := elem.Type().Field(i)
-				if tag := field.Tag.Get("ctxboot"); tag == "inject" {
-					fieldType := field.Type
-					if fieldType.Kind() != reflect.Ptr {
-						fieldType = reflect.PtrTo(fieldType)
-					}
-
-					if _, exists := components[fieldType]; exists && !initialized[fieldType] {
-						allDepsInitialized = false
-						break
-					}
+			for _, dep := range deps {
+				if !initialized[dep] {
+					allDepsInitialized = false
+					break
 				}
 			}
 
 			if allDepsInitialized {
-				if err := c.injectDependencies(instance); err != nil {
-					return fmt.Errorf("failed to initialize component %v: %w", typ, err)
+				started, err := c.startComponent(key, resumed[key], containsAny(deps, fresh))
+				if err != nil {
+					return err
+				}
+				if started {
+					fresh[key] = true
+					if !resumed[key] {
+						initOrder = append(initOrder, key)
+					}
 				}
-				initialized[typ] = true
+				initialized[key] = true
 				progress = true
 			}
 		}
 
 		if !progress {
-			// Find uninitialized components for error message
-			var uninitialized []string
-			for typ := range components {
-				if !initialized[typ] {
-					uninitialized = append(uninitialized, typ.String())
+			// Report the cycles among the uninitialized components
+			var uninitialized []componentKey
+			for _, key := range order {
+				if !initialized[key] {
+					uninitialized = append(uninitialized, key)
 				}
 			}
-			return fmt.Errorf("circular dependency detected among: %v", uninitialized)
+			return c.cycleError(uninitialized)
 		}
 	}
 
 	return nil
 }
 
-// injectDependencies injects dependencies into a component
-func (c *CtxbootComponentContext) injectDependencies(target interface{}) error {
+// startComponent initializes the component registered under key during
+// InitializeComponents, and reports whether a new instance was initialized.
+// A component resumed from a failed attempt is not initialized again: it is
+// only refreshed when stale, one of its dependencies having been initialized
+// since
+func (c *CtxbootComponentContext) startComponent(key componentKey, resumed, stale bool) (bool, error) {
+	switch {
+	case c.isPrototype(key):
+		// Prototypes are created on demand, only their dependencies have to
+		// be initialized first
+		return false, nil
+	case !resumed:
+		return true, c.initializeComponent(key)
+	case stale:
+		return c.refreshComponent(key)
+	}
+	return false, nil
+}
+
+// componentDependencies returns the registered components the component
+// registered under key depends on, see dependencySteps
+func (c *CtxbootComponentContext) componentDependencies(key componentKey) ([]componentKey, error) {
+	steps, err := c.dependencySteps(key)
+	if err != nil {
+		return nil, err
+	}
+	deps := make([]componentKey, len(steps))
+	for i, step := range steps {
+		deps[i] = step.dep
+	}
+	return deps, nil
+}
+
+// initializeComponent calls the provider of a provided component that was
+// not created yet, or injects the dependencies of any other component, and
+// then runs its Init hook
+func (c *CtxbootComponentContext) initializeComponent(key componentKey) (err error) {
+	start := c.initStarted(key)
+	defer func() { c.initDone(key, start, err) }()
+
+	c.mu.RLock()
+	instance, exists := c.components[key]
+	provider, isProvided := c.providers[key]
+	c.mu.RUnlock()
+
+	switch {
+	case isProvided && !exists:
+		provided, err := c.callProvider(provider)
+		if err != nil {
+			return fmt.Errorf("failed to initialize component %v: %w", key, err)
+		}
+		instance = provided
+
+		c.mu.Lock()
+		c.components[key] = instance
+		c.mu.Unlock()
+	case !isProvided:
+		if err := c.bindProperties(key, instance); err != nil {
+			return fmt.Errorf("failed to initialize component %v: %w", key, err)
+		}
+		if err := c.injectDependencies(key, instance); err != nil {
+			return fmt.Errorf("failed to initialize component %v: %w", key, err)
+		}
+	}
+
+	if err := runInitHook(instance); err != nil {
+		return fmt.Errorf("failed to initialize component %v: init hook: %w", key, err)
+	}
+	c.events.subscribe(key, instance)
+	return nil
+}
+
+// injectionPoint is an inject field or a provider parameter of a component
+type injectionPoint struct {
+	field     string       // field name, or provider parameter as #i
+	step      string       // as named in errors, e.g. repository.UserRepository.db
+	valueType reflect.Type // type to inject, the type argument of a deferred field
+	opts      injectTag
+	deferred  bool // Lazy or Provider field, resolved on use
+}
+
+// injectionPoints returns the injection points of the component registered
+// under key: the parameters of its provider, or else its inject fields
+func (c *CtxbootComponentContext) injectionPoints(key componentKey) ([]injectionPoint, error) {
+	c.mu.RLock()
+	provider, isProvided := c.providers[key]
+	c.mu.RUnlock()
+
+	if isProvided {
+		fnType := provider.Type()
+		points := make([]injectionPoint, fnType.NumIn())
+		for i := range points {
+			field := fmt.Sprintf("#%d", i)
+			points[i] = injectionPoint{field: field, step: funcName(provider) + field, valueType: fnType.In(i)}
+		}
+		return points, nil
+	}
+
+	typ, err := c.componentStruct(key)
+	if err != nil {
+		return nil, err
+	}
+	var points []injectionPoint
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		opts, ok := parseInjectTag(field.Tag)
+		if !ok {
+			continue
+		}
+		point := injectionPoint{field: field.Name, step: fieldStep(typ, field.Name), valueType: field.Type, opts: opts}
+		if isDeferred(field.Type) {
+			point.valueType = reflect.Zero(reflect.PtrTo(field.Type)).Interface().(deferred).elemType()
+			point.deferred = true
+		}
+		points = append(points, point)
+	}
+	return points, nil
+}
+
+// componentStruct returns the struct type of the component registered under
+// key, which must be a prototype or a pointer to a struct
+func (c *CtxbootComponentContext) componentStruct(key componentKey) (reflect.Type, error) {
+	c.mu.RLock()
+	instance := c.components[key]
+	_, isPrototype := c.prototypes[key]
+	c.mu.RUnlock()
+
+	if isPrototype {
+		return key.typ.Elem(), nil
+	}
+	if typ := reflect.TypeOf(instance); typ != nil && typ.Kind() == reflect.Ptr && typ.Elem().Kind() == reflect.Struct {
+		return typ.Elem(), nil
+	}
+	return nil, newError(ErrNotAssignable, key, "component must be a pointer to a struct, got %T", instance)
+}
+
+// valueDependencies returns the registered components a value of type
+// valueType is resolved from. Caller must hold c.mu
+func (c *CtxbootComponentContext) valueDependencies(valueType reflect.Type, opts injectTag) []componentKey {
+	// Collections depend on every matching component
+	if elemType, ok := collectionElem(valueType); ok {
+		return c.collectionKeys(lookupType(elemType))
+	}
+
+	if key, err := c.resolve(lookupType(valueType), opts.name); err == nil {
+		return []componentKey{key}
+	}
+	return nil
+}
+
+// lookupType returns the type under which a component assignable to a field
+// of type fieldType is registered: interfaces and pointers are used directly,
+// other types are looked up as pointers
+func lookupType(fieldType reflect.Type) reflect.Type {
+	if fieldType.Kind() != reflect.Ptr && fieldType.Kind() != reflect.Interface {
+		return reflect.PtrTo(fieldType)
+	}
+	return fieldType
+}
+
+// injectDependencies injects dependencies into the component registered
+// under key and sets its value fields from the properties
+func (c *CtxbootComponentContext) injectDependencies(key componentKey, target interface{}) error {
 	val := reflect.ValueOf(target)
 	if val.Kind() != reflect.Ptr {
 		return fmt.Errorf("target must be a pointer")
@@ -176,56 +595,72 @@ func (c *CtxbootComponentContext) injectDependencies(target interface{}) error {
 	typ := elem.Type()
 	for i := 0; i < typ.NumField(); i++ {
 		field := typ.Field(i)
-		if tag := field.Tag.Get("ctxboot"); tag == "inject" {
-			// Get the type for the field
-			fieldType := field.Type
-			isPtrField := fieldType.Kind() == reflect.Ptr
-
-			// For interface fields, use the interface type directly
-			if fieldType.Kind() == reflect.Interface {
-				component, err := c.GetComponent(fieldType)
-				if err != nil {
-					return fmt.Errorf("failed to inject field %s: %w", field.Name, err)
-				}
-
-				fieldVal := elem.Field(i)
-				if !fieldVal.CanSet() {
-					// Handle unexported field
-					fieldVal = reflect.NewAt(field.Type, unsafe.Pointer(fieldVal.UnsafeAddr())).Elem()
-				}
-
-				// Set the value
-				fieldVal.Set(reflect.ValueOf(component))
-				continue
-			}
-
-			// For non-interface fields
-			lookupType := fieldType
-			if !isPtrField {
-				lookupType = reflect.PtrTo(fieldType)
-			}
+		fieldVal := elem.Field(i)
+		if !fieldVal.CanSet() {
+			// Handle unexported field
+			fieldVal = reflect.NewAt(field.Type, unsafe.Pointer(fieldVal.UnsafeAddr())).Elem()
+		}
 
-			component, err := c.GetComponent(lookupType)
+		if opts, ok := parseValueTag(field.Tag); ok {
+			value, err := c.propertyValue(field.Type, opts)
+			c.notify(func(o Observer) { o.OnInjectField(key.id(), field.Name, err) })
 			if err != nil {
-				return fmt.Errorf("failed to inject field %s: %w", field.Name, err)
+				return withPath(err, fieldStep(typ, field.Name))
 			}
+			fieldVal.Set(value)
+			continue
+		}
 
-			fieldVal := elem.Field(i)
-			if !fieldVal.CanSet() {
-				// Handle unexported field
-				fieldVal = reflect.NewAt(field.Type, unsafe.Pointer(fieldVal.UnsafeAddr())).Elem()
+		if opts, ok := parseInjectTag(field.Tag); ok {
+			// Bind Lazy and Provider fields to a resolver
+			if isDeferred(field.Type) {
+				d := fieldVal.Addr().Interface().(deferred)
+				elemType := d.elemType()
+				d.bind(func() (reflect.Value, error) {
+					return c.fieldValue(elemType, opts)
+				})
+				c.notify(func(o Observer) { o.OnInjectField(key.id(), field.Name, nil) })
+				continue
 			}
 
-			// Convert component to the correct type
-			compVal := reflect.ValueOf(component)
-			if !isPtrField {
-				// If field is not a pointer, dereference the component
-				compVal = compVal.Elem()
+			value, err := c.fieldValue(field.Type, opts)
+			c.notify(func(o Observer) { o.OnInjectField(key.id(), field.Name, err) })
+			if err != nil {
+				return withPath(err, fieldStep(typ, field.Name))
 			}
 
 			// Set the value
-			fieldVal.Set(compVal)
+			fieldVal.Set(value)
 		}
 	}
 	return nil
 }
+
+// fieldValue resolves the value to inject into a field of type fieldType
+func (c *CtxbootComponentContext) fieldValue(fieldType reflect.Type, opts injectTag) (reflect.Value, error) {
+	// Slices and maps receive every matching component, at least one unless
+	// the field is optional
+	if elemType, ok := collectionElem(fieldType); ok {
+		collection, err := c.collectionValue(fieldType)
+		if err == nil && collection.Len() == 0 && !opts.optional {
+			err = newError(ErrComponentNotFound, componentKey{typ: lookupType(elemType)}, "no component for %v", fieldType)
+		}
+		return collection, err
+	}
+
+	component, err := c.GetNamedComponent(opts.name, lookupType(fieldType))
+	if opts.optional && isMissing(err) {
+		return reflect.Zero(fieldType), nil
+	}
+	if err != nil {
+		return reflect.Value{}, err
+	}
+
+	// Convert component to the correct type
+	compVal := reflect.ValueOf(component)
+	if fieldType.Kind() != reflect.Ptr && fieldType.Kind() != reflect.Interface {
+		// If field is not a pointer, dereference the component
+		compVal = compVal.Elem()
+	}
+	return compVal, nil
+}
@@ -0,0 +1,88 @@
+package ctxboot
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type pointsService struct {
+	Free   *cycFree           `ctxboot:"inject,name=free,optional"`
+	Later  Lazy[*cycFree]     `ctxboot:"inject"`
+	All    []*cycFree         `ctxboot:"inject"`
+	Port   int                `ctxboot:"value=port,default=80"`
+	Events Publisher          `ctxboot:"inject"`
+	Each   Provider[*cycFree] `ctxboot:"inject"`
+}
+
+func newPointsFree(s *pointsService, d time.Duration) *cycFree {
+	return &cycFree{}
+}
+
+func TestInjectionPoints(t *testing.T) {
+	c := NewCtxbootComponentContext()
+	mustRegister(t, c, &pointsService{})
+	if err := c.SetNamedProvider("provided", newPointsFree); err != nil {
+		t.Fatal(err)
+	}
+	duration := time.Second
+	mustRegister(t, c, &duration)
+
+	type point struct {
+		Field    string
+		Step     string
+		Type     reflect.Type
+		Opts     injectTag
+		Deferred bool
+	}
+	pointsOf := func(typ reflect.Type, name string) []point {
+		t.Helper()
+		points, err := c.injectionPoints(componentKey{typ: typ, name: name})
+		if err != nil {
+			t.Fatal(err)
+		}
+		result := make([]point, len(points))
+		for i, p := range points {
+			result[i] = point{p.field, p.step, p.valueType, p.opts, p.deferred}
+		}
+		return result
+	}
+
+	free := reflect.TypeOf(&cycFree{})
+	got := pointsOf(reflect.TypeOf(&pointsService{}), "")
+	want := []point{
+		{"Free", "ctxboot.pointsService.Free", free, injectTag{name: "free", optional: true}, false},
+		{"Later", "ctxboot.pointsService.Later", free, injectTag{}, true},
+		{"All", "ctxboot.pointsService.All", reflect.TypeOf([]*cycFree{}), injectTag{}, false},
+		{"Events", "ctxboot.pointsService.Events", publisherType, injectTag{}, false},
+		{"Each", "ctxboot.pointsService.Each", free, injectTag{}, true},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("injectionPoints(service) = %v, want %v", got, want)
+	}
+
+	got = pointsOf(free, "provided")
+	want = []point{
+		{"#0", "github.com/iondodon/ctxboot.newPointsFree#0", reflect.TypeOf(&pointsService{}), injectTag{}, false},
+		{"#1", "github.com/iondodon/ctxboot.newPointsFree#1", reflect.TypeOf(duration), injectTag{}, false},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("injectionPoints(provider) = %v, want %v", got, want)
+	}
+
+	// Components that are not structs are reported the same way everywhere
+	key := componentKey{typ: reflect.TypeOf(&duration)}
+	if _, err := c.injectionPoints(key); !errors.Is(err, ErrNotAssignable) {
+		t.Errorf("injectionPoints(*time.Duration) error = %v, want ErrNotAssignable", err)
+	}
+	if err := c.Validate(); !errors.Is(err, ErrNotAssignable) {
+		t.Errorf("Validate() = %v, want ErrNotAssignable", err)
+	}
+	if err := c.InitializeComponents(); !errors.Is(err, ErrNotAssignable) {
+		t.Errorf("InitializeComponents() = %v, want ErrNotAssignable", err)
+	}
+	if edges := c.graphEdges(key); len(edges) != 0 {
+		t.Errorf("graphEdges(*time.Duration) = %v, want none", edges)
+	}
+}
@@ -0,0 +1,63 @@
+// Package ctxboottest helps tests swap the components of a ctxboot context
+// for fakes, restoring them when the test ends:
+//
+//	func TestSignup(t *testing.T) {
+//	    mailer := &FakeMailer{}
+//	    ctxboottest.Override[Mailer](t, cc, mailer)
+//	    ...
+//	}
+package ctxboottest
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/iondodon/ctxboot"
+)
+
+// Override replaces the component resolving to T with v for the duration of
+// the test, see ctxboot.CtxbootComponentContext.WithOverrides
+func Override[T any](t testing.TB, c ctxboot.Container, v T) {
+	t.Helper()
+	restore, err := ctxboot.Override[T](c, v)
+	if err != nil {
+		t.Fatalf("ctxboottest: override %v: %v", reflect.TypeOf((*T)(nil)).Elem(), err)
+	}
+	cleanup(t, restore)
+}
+
+// WithOverrides replaces the components resolving to the types of overrides
+// for the duration of the test
+func WithOverrides(t testing.TB, c Context, overrides map[reflect.Type]interface{}) {
+	t.Helper()
+	restore, err := c.WithOverrides(overrides)
+	if err != nil {
+		t.Fatalf("ctxboottest: overrides: %v", err)
+	}
+	cleanup(t, restore)
+}
+
+// Snapshot restores the registrations of c when the test ends, undoing the
+// components registered by the test
+func Snapshot(t testing.TB, c Context) {
+	t.Helper()
+	snapshot := c.Snapshot()
+	cleanup(t, func() error { return c.Restore(snapshot) })
+}
+
+// Context is implemented by ctxboot.CtxbootComponentContext and by the
+// generated ComponentContext embedding it
+type Context interface {
+	Snapshot() *ctxboot.Snapshot
+	Restore(s *ctxboot.Snapshot) error
+	WithOverrides(overrides map[reflect.Type]interface{}) (restore func() error, err error)
+}
+
+// cleanup calls restore when the test ends, failing it on error
+func cleanup(t testing.TB, restore func() error) {
+	t.Cleanup(func() {
+		if err := restore(); err != nil {
+			t.Errorf("ctxboottest: restore: %v", err)
+		}
+	})
+}
@@ -0,0 +1,122 @@
+package ctxboottest_test
+
+import (
+	"fmt"
+	"reflect"
+	"runtime"
+	"testing"
+
+	"github.com/iondodon/ctxboot"
+	"github.com/iondodon/ctxboot/ctxboottest"
+)
+
+type Mailer interface {
+	Send(to string)
+}
+
+type SMTPMailer struct{ host string }
+
+func (*SMTPMailer) Send(to string) {}
+
+type FakeMailer struct{ sent []string }
+
+func (f *FakeMailer) Send(to string) { f.sent = append(f.sent, to) }
+
+type Signup struct {
+	Mailer Mailer `ctxboot:"inject"`
+}
+
+func newContext(t *testing.T) (*ctxboot.CtxbootComponentContext, *SMTPMailer, *Signup) {
+	t.Helper()
+	c := ctxboot.NewCtxbootComponentContext()
+	smtp, signup := &SMTPMailer{host: "smtp"}, &Signup{}
+	for _, instance := range []interface{}{smtp, signup} {
+		if err := c.SetComponent(reflect.TypeOf(instance), instance); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := c.InitializeComponents(); err != nil {
+		t.Fatal(err)
+	}
+	return c, smtp, signup
+}
+
+func TestOverride(t *testing.T) {
+	c, smtp, signup := newContext(t)
+	fake := &FakeMailer{}
+	t.Run("overridden", func(t *testing.T) {
+		ctxboottest.Override[Mailer](t, c, fake)
+		if signup.Mailer != fake {
+			t.Errorf("Mailer = %v, want the fake", signup.Mailer)
+		}
+	})
+	if signup.Mailer != smtp {
+		t.Errorf("Mailer = %v after the test, want the original", signup.Mailer)
+	}
+}
+
+func TestWithOverrides(t *testing.T) {
+	c, smtp, signup := newContext(t)
+	fake := &FakeMailer{}
+	t.Run("overridden", func(t *testing.T) {
+		ctxboottest.WithOverrides(t, c, map[reflect.Type]interface{}{
+			reflect.TypeOf((*Mailer)(nil)).Elem(): fake,
+		})
+		if got := ctxboot.MustGet[Mailer](c); got != fake {
+			t.Errorf("Get() = %v, want the fake", got)
+		}
+	})
+	if got := ctxboot.MustGet[Mailer](c); got != smtp || signup.Mailer != smtp {
+		t.Errorf("Get() = %v after the test, want the original", got)
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	c := ctxboot.NewCtxbootComponentContext()
+	t.Run("registering", func(t *testing.T) {
+		ctxboottest.Snapshot(t, c)
+		if err := c.SetComponent(reflect.TypeOf(&SMTPMailer{}), &SMTPMailer{}); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if _, err := ctxboot.Get[*SMTPMailer](c); err == nil {
+		t.Error("the component registered by the test was not undone")
+	}
+}
+
+// recorder is a testing.TB recording failures and cleanups instead of
+// running them. Fatalf exits the goroutine like testing.T
+type recorder struct {
+	testing.TB
+	failures []string
+	cleanups []func()
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Fatalf(format string, args ...interface{}) {
+	r.failures = append(r.failures, fmt.Sprintf(format, args...))
+	runtime.Goexit()
+}
+
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.failures = append(r.failures, fmt.Sprintf(format, args...))
+}
+
+func (r *recorder) Cleanup(fn func()) {
+	r.cleanups = append(r.cleanups, fn)
+}
+
+func TestOverrideFailure(t *testing.T) {
+	c, _, _ := newContext(t)
+	r := &recorder{TB: t}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ctxboottest.Override[Mailer](r, c, nil)
+	}()
+	<-done
+	if len(r.failures) != 1 || len(r.cleanups) != 0 {
+		t.Errorf("failures = %v with %d cleanups, want the nil override reported", r.failures, len(r.cleanups))
+	}
+}
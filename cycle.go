@@ -0,0 +1,204 @@
+package ctxboot
+
+import (
+	"fmt"
+	"strings"
+)
+
+// dependencyStep is a dependency of a component on another, through one of
+// its inject fields or provider parameters
+type dependencyStep struct {
+	step string // field or parameter, e.g. repository.UserRepository.db
+	dep  componentKey
+}
+
+// dependencySteps returns the registered components the component registered
+// under key depends on, through the injection points that are not deferred.
+// Injection points that do not resolve are left for injectDependencies to
+// report
+func (c *CtxbootComponentContext) dependencySteps(key componentKey) ([]dependencyStep, error) {
+	points, err := c.injectionPoints(key)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	var steps []dependencyStep
+	for _, point := range points {
+		if point.deferred {
+			continue
+		}
+		for _, dep := range c.valueDependencies(point.valueType, point.opts) {
+			steps = append(steps, dependencyStep{step: point.step, dep: dep})
+		}
+	}
+	return steps, nil
+}
+
+// cycleError reports the dependency cycles keeping the components registered
+// under keys from being initialized. Components that only depend on a cycle
+// are not reported
+func (c *CtxbootComponentContext) cycleError(keys []componentKey) error {
+	cycles := c.dependencyCycles(keys)
+	if len(cycles) == 0 {
+		names := make([]string, len(keys))
+		for i, key := range keys {
+			names[i] = key.String()
+		}
+		return &Error{Err: ErrCircularDependency, Detail: fmt.Sprintf("among %v", names)}
+	}
+
+	err := &Error{Err: ErrCircularDependency, Path: cycles[0]}
+	if len(cycles) > 1 {
+		others := make([]string, len(cycles)-1)
+		for i, cycle := range cycles[1:] {
+			others[i] = strings.Join(cycle, " -> ")
+		}
+		err.Detail = "also " + strings.Join(others, "; ")
+	}
+	return err
+}
+
+// dependencyCycles returns one cycle per strongly connected group of the
+// components registered under keys, as the fields and parameters of the
+// cycle followed by its first component, e.g.
+// [a.A.b a.B.c a.C.a *a.A]. The cycles are the shortest ones through the
+// first component of each group in keys order
+func (c *CtxbootComponentContext) dependencyCycles(keys []componentKey) [][]string {
+	position := make(map[componentKey]int, len(keys))
+	for i, key := range keys {
+		position[key] = i
+	}
+
+	// Dependencies among keys only
+	edges := make(map[componentKey][]dependencyStep, len(keys))
+	for _, key := range keys {
+		// Components without injection points have no dependencies
+		steps, _ := c.dependencySteps(key)
+		for _, step := range steps {
+			if _, ok := position[step.dep]; ok {
+				edges[key] = append(edges[key], step)
+			}
+		}
+	}
+
+	// Tarjan's algorithm finds the strongly connected groups
+	index := make(map[componentKey]int)
+	low := make(map[componentKey]int)
+	onStack := make(map[componentKey]bool)
+	var stack []componentKey
+	var groups [][]componentKey
+
+	var connect func(key componentKey)
+	connect = func(key componentKey) {
+		index[key] = len(index)
+		low[key] = index[key]
+		stack = append(stack, key)
+		onStack[key] = true
+
+		for _, step := range edges[key] {
+			if _, visited := index[step.dep]; !visited {
+				connect(step.dep)
+				low[key] = min(low[key], low[step.dep])
+			} else if onStack[step.dep] {
+				low[key] = min(low[key], index[step.dep])
+			}
+		}
+
+		if low[key] == index[key] {
+			var group []componentKey
+			for {
+				top := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				onStack[top] = false
+				group = append(group, top)
+				if top == key {
+					break
+				}
+			}
+			groups = append(groups, group)
+		}
+	}
+	for _, key := range keys {
+		if _, visited := index[key]; !visited {
+			connect(key)
+		}
+	}
+
+	// Report the groups in keys order
+	firsts := make([]componentKey, 0, len(groups))
+	members := make(map[componentKey]componentKey) // member -> first of its group
+	for _, group := range groups {
+		first := group[0]
+		for _, key := range group {
+			if position[key] < position[first] {
+				first = key
+			}
+		}
+		for _, key := range group {
+			members[key] = first
+		}
+		if len(group) > 1 || selfDependent(first, edges) {
+			firsts = append(firsts, first)
+		}
+	}
+	sortKeys(firsts, position)
+
+	cycles := make([][]string, 0, len(firsts))
+	for _, first := range firsts {
+		cycles = append(cycles, shortestCycle(first, edges, func(key componentKey) bool {
+			return members[key] == first
+		}))
+	}
+	return cycles
+}
+
+// selfDependent reports whether key depends on itself
+func selfDependent(key componentKey, edges map[componentKey][]dependencyStep) bool {
+	for _, step := range edges[key] {
+		if step.dep == key {
+			return true
+		}
+	}
+	return false
+}
+
+// sortKeys sorts keys by position
+func sortKeys(keys []componentKey, position map[componentKey]int) {
+	for i := 1; i < len(keys); i++ {
+		for j := i; j > 0 && position[keys[j]] < position[keys[j-1]]; j-- {
+			keys[j], keys[j-1] = keys[j-1], keys[j]
+		}
+	}
+}
+
+// shortestCycle returns the shortest cycle from start back to it through the
+// components accepted by inGroup, found breadth first
+func shortestCycle(start componentKey, edges map[componentKey][]dependencyStep, inGroup func(componentKey) bool) []string {
+	type visit struct {
+		from componentKey
+		step string
+	}
+	prev := make(map[componentKey]visit)
+	queue := []componentKey{start}
+	for len(queue) > 0 {
+		key := queue[0]
+		queue = queue[1:]
+		for _, step := range edges[key] {
+			if step.dep == start {
+				// Walk back to start
+				cycle := []string{step.step, start.String()}
+				for k := key; k != start; k = prev[k].from {
+					cycle = append([]string{prev[k].step}, cycle...)
+				}
+				return cycle
+			}
+			if _, seen := prev[step.dep]; !seen && inGroup(step.dep) {
+				prev[step.dep] = visit{from: key, step: step.step}
+				queue = append(queue, step.dep)
+			}
+		}
+	}
+	return []string{start.String()}
+}
@@ -0,0 +1,119 @@
+package ctxboot
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type cycA struct {
+	B *cycB `ctxboot:"inject"`
+	C *cycC `ctxboot:"inject"`
+}
+
+type cycB struct {
+	C *cycC `ctxboot:"inject"`
+}
+
+type cycC struct {
+	A *cycA `ctxboot:"inject"`
+}
+
+type cycSelf struct {
+	Self *cycSelf `ctxboot:"inject"`
+}
+
+type cycX struct {
+	Y *cycY `ctxboot:"inject"`
+}
+
+type cycY struct {
+	X *cycX `ctxboot:"inject"`
+}
+
+type cycUser struct {
+	A *cycA `ctxboot:"inject"`
+	X *cycX `ctxboot:"inject"`
+}
+
+type cycFree struct{}
+
+func cycleKeys(instances ...interface{}) []componentKey {
+	keys := make([]componentKey, len(instances))
+	for i, instance := range instances {
+		keys[i] = componentKey{typ: reflect.TypeOf(instance)}
+	}
+	return keys
+}
+
+func TestDependencyCycles(t *testing.T) {
+	c := NewCtxbootComponentContext()
+	instances := []interface{}{&cycUser{}, &cycFree{}, &cycX{}, &cycA{}, &cycB{}, &cycC{}, &cycSelf{}, &cycY{}}
+	for _, instance := range instances {
+		mustRegister(t, c, instance)
+	}
+
+	// One cycle per group in keys order, the shortest through its first
+	// member: A -> C -> A rather than A -> B -> C -> A. Components depending
+	// on a cycle are left out
+	got := c.dependencyCycles(cycleKeys(instances...))
+	want := [][]string{
+		{"ctxboot.cycX.Y", "ctxboot.cycY.X", "*ctxboot.cycX"},
+		{"ctxboot.cycA.C", "ctxboot.cycC.A", "*ctxboot.cycA"},
+		{"ctxboot.cycSelf.Self", "*ctxboot.cycSelf"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("dependencyCycles() = %v, want %v", got, want)
+	}
+
+	// Dependencies outside of keys are ignored
+	if got := c.dependencyCycles(cycleKeys(&cycA{}, &cycB{}, &cycUser{})); len(got) != 0 {
+		t.Errorf("dependencyCycles() = %v, want no cycle", got)
+	}
+}
+
+func TestCycleError(t *testing.T) {
+	c := NewCtxbootComponentContext()
+	for _, instance := range []interface{}{&cycA{}, &cycB{}, &cycC{}, &cycX{}, &cycY{}, &cycFree{}} {
+		mustRegister(t, c, instance)
+	}
+
+	err := c.cycleError(cycleKeys(&cycA{}, &cycB{}, &cycC{}, &cycX{}, &cycY{}))
+	want := "circular dependency: ctxboot.cycA.C -> ctxboot.cycC.A -> *ctxboot.cycA (also ctxboot.cycX.Y -> ctxboot.cycY.X -> *ctxboot.cycX)"
+	if !errors.Is(err, ErrCircularDependency) || err.Error() != want {
+		t.Errorf("cycleError() = %v, want %s", err, want)
+	}
+
+	// Without a cycle the components are listed
+	err = c.cycleError(cycleKeys(&cycFree{}))
+	want = "circular dependency (among [*ctxboot.cycFree])"
+	if !errors.Is(err, ErrCircularDependency) || err.Error() != want {
+		t.Errorf("cycleError() = %v, want %s", err, want)
+	}
+}
+
+func TestShortestCycle(t *testing.T) {
+	keys := cycleKeys(&cycA{}, &cycB{}, &cycC{}, &cycFree{})
+	a, b, c, d := keys[0], keys[1], keys[2], keys[3]
+	edges := map[componentKey][]dependencyStep{
+		a: {{step: "a.b", dep: b}, {step: "a.d", dep: d}},
+		b: {{step: "b.c", dep: c}},
+		c: {{step: "c.a", dep: a}},
+		d: {{step: "d.a", dep: a}},
+	}
+
+	inGroup := func(componentKey) bool { return true }
+	if got, want := shortestCycle(a, edges, inGroup), []string{"a.d", "d.a", "*ctxboot.cycA"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("shortestCycle() = %v, want %v", got, want)
+	}
+
+	// Components outside of the group are not followed
+	notD := func(key componentKey) bool { return key != d }
+	if got, want := shortestCycle(a, edges, notD), []string{"a.b", "b.c", "c.a", "*ctxboot.cycA"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("shortestCycle() = %v, want %v", got, want)
+	}
+
+	if got, want := shortestCycle(b, map[componentKey][]dependencyStep{}, inGroup), []string{"*ctxboot.cycB"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("shortestCycle() = %v, want %v", got, want)
+	}
+}
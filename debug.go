@@ -0,0 +1,242 @@
+package ctxboot
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// DebugHandler returns an http.Handler exposing the runtime registry of c
+// and its ancestors, like Spring's actuator:
+//
+//	/components  the components with their type, package, scope, state,
+//	             initialization time and injected dependencies, as JSON
+//	/graph       the dependency graph, as DOT or ?format=mermaid or json
+//	/timings     the initialization times, slowest first, as JSON
+//	/health      the health report, see HealthHandler
+//
+// It is meant to be mounted under a prefix on an internal port:
+//
+//	mux.Handle("/debug/ctxboot/", http.StripPrefix("/debug/ctxboot", cc.DebugHandler()))
+func (c *CtxbootComponentContext) DebugHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" && r.URL.Path != "" {
+			http.NotFound(w, r)
+			return
+		}
+		writeJSON(w, map[string]interface{}{
+			"state":     c.State().String(),
+			"endpoints": []string{"components", "graph", "timings", "health"},
+		})
+	})
+	mux.HandleFunc("/components", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, c.debugComponents())
+	})
+	mux.HandleFunc("/graph", func(w http.ResponseWriter, r *http.Request) {
+		format := r.URL.Query().Get("format")
+		switch format {
+		case "", "dot":
+			format = "dot"
+			w.Header().Set("Content-Type", "text/vnd.graphviz; charset=utf-8")
+		case "mermaid":
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		case "json":
+			w.Header().Set("Content-Type", "application/json")
+		default:
+			http.Error(w, fmt.Sprintf("unknown graph format %q, expected dot, mermaid or json", format), http.StatusBadRequest)
+			return
+		}
+		c.Graph().Write(w, format)
+	})
+	mux.HandleFunc("/timings", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, c.debugTimings())
+	})
+	mux.Handle("/health", c.HealthHandler(""))
+	return mux
+}
+
+// debugComponent describes a component in DebugHandler
+type debugComponent struct {
+	ID           string            `json:"id"`
+	Type         string            `json:"type"`
+	Package      string            `json:"package"`
+	Qualifier    string            `json:"qualifier,omitempty"`
+	Kind         string            `json:"kind"` // component or provider
+	Scope        string            `json:"scope"`
+	Primary      bool              `json:"primary,omitempty"`
+	State        string            `json:"state"` // registered, initialized, failed, stopped or prototype
+	InitDuration string            `json:"initDuration,omitempty"`
+	InitError    string            `json:"initError,omitempty"`
+	Dependencies []debugDependency `json:"dependencies"`
+}
+
+// debugDependency is an injected dependency of a component in DebugHandler
+type debugDependency struct {
+	Field      string `json:"field"`
+	Component  string `json:"component"`
+	Kind       string `json:"kind,omitempty"` // component, provider or missing
+	Collection bool   `json:"collection,omitempty"`
+	Optional   bool   `json:"optional,omitempty"`
+	Deferred   bool   `json:"deferred,omitempty"`
+}
+
+// debugComponents describes the components of c and its ancestors, those of
+// a child overriding its ancestors', in registration order
+func (c *CtxbootComponentContext) debugComponents() []debugComponent {
+	g := c.Graph()
+	nodes := make(map[string]GraphNode, len(g.Nodes))
+	for _, node := range g.Nodes {
+		nodes[node.ID] = node
+	}
+
+	components := []debugComponent{}
+	seen := make(map[componentKey]bool)
+	for ctx := c; ctx != nil; ctx = ctx.parent {
+		ctx.mu.RLock()
+		keys := append([]componentKey(nil), ctx.order...)
+		ctx.mu.RUnlock()
+
+		for _, key := range keys {
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+
+			node := nodes[key.String()]
+			ctx.mu.RLock()
+			component := debugComponent{
+				ID:        node.ID,
+				Type:      node.Type,
+				Package:   node.Package,
+				Qualifier: node.Qualifier,
+				Kind:      node.Kind,
+				Scope:     node.Scope,
+				Primary:   node.Primary,
+				State:     ctx.componentState(key),
+			}
+			if init, ok := ctx.inits[key]; ok {
+				component.InitDuration = init.Duration.String()
+				if init.Err != nil {
+					component.InitError = init.Err.Error()
+				}
+			}
+			ctx.mu.RUnlock()
+
+			component.Dependencies = ctx.debugDependencies(key, nodes)
+			components = append(components, component)
+		}
+	}
+	return components
+}
+
+// debugDependencies describes the components injected into the component
+// registered under key: those its injection points resolve to, every
+// candidate of an ambiguous one, or the missing type of an unresolvable one
+func (c *CtxbootComponentContext) debugDependencies(key componentKey, nodes map[string]GraphNode) []debugDependency {
+	dependencies := []debugDependency{}
+	for _, edge := range c.graphEdges(key) {
+		var targets []componentKey
+		if edge.Collection {
+			for _, entry := range c.collectionEntries(edge.target) {
+				targets = append(targets, entry.key)
+			}
+		} else {
+			targets = c.locate(edge.target, edge.Qualifier)
+		}
+
+		dependency := debugDependency{
+			Field:      edge.Field,
+			Collection: edge.Collection,
+			Optional:   edge.Optional,
+			Deferred:   edge.Deferred,
+		}
+		if len(targets) == 0 && !edge.Collection {
+			dependency.Component = componentKey{typ: edge.target, name: edge.Qualifier}.String()
+			dependency.Kind = NodeMissing
+			dependencies = append(dependencies, dependency)
+		}
+		for _, target := range targets {
+			dependency.Component = target.String()
+			dependency.Kind = nodes[dependency.Component].Kind
+			dependencies = append(dependencies, dependency)
+		}
+	}
+	return dependencies
+}
+
+// componentState describes the state of the component registered under key.
+// Caller must hold c.mu
+func (c *CtxbootComponentContext) componentState(key componentKey) string {
+	if _, ok := c.prototypes[key]; ok {
+		return "prototype"
+	}
+	init, initialized := c.inits[key]
+	switch {
+	case initialized && init.Err != nil:
+		return "failed"
+	case initialized && c.state == StateShutDown:
+		return "stopped"
+	case containsKey(c.initOrder, key):
+		return "initialized"
+	}
+	return "registered"
+}
+
+// debugTiming is the initialization time of a component in DebugHandler
+type debugTiming struct {
+	Component string  `json:"component"`
+	Duration  string  `json:"duration"`
+	Share     float64 `json:"share"` // percentage of the total
+	Error     string  `json:"error,omitempty"`
+}
+
+// debugTimings describes the initialization times of the components of c
+// and its ancestors, slowest first
+func (c *CtxbootComponentContext) debugTimings() interface{} {
+	var timings []InitTiming
+	for ctx := c; ctx != nil; ctx = ctx.parent {
+		ctx.mu.RLock()
+		for _, timing := range ctx.inits {
+			timings = append(timings, timing)
+		}
+		ctx.mu.RUnlock()
+	}
+	sort.Slice(timings, func(i, j int) bool {
+		if timings[i].Duration != timings[j].Duration {
+			return timings[i].Duration > timings[j].Duration
+		}
+		return timings[i].Component.String() < timings[j].Component.String()
+	})
+
+	var total time.Duration
+	for _, timing := range timings {
+		total += timing.Duration
+	}
+	components := make([]debugTiming, len(timings))
+	for i, timing := range timings {
+		components[i] = debugTiming{Component: timing.Component.String(), Duration: timing.Duration.String()}
+		if total > 0 {
+			components[i].Share = 100 * float64(timing.Duration) / float64(total)
+		}
+		if timing.Err != nil {
+			components[i].Error = timing.Err.Error()
+		}
+	}
+	return struct {
+		Total      string        `json:"total"`
+		Components []debugTiming `json:"components"`
+	}{Total: total.String(), Components: components}
+}
+
+// writeJSON writes v as indented JSON
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
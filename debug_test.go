@@ -0,0 +1,139 @@
+package ctxboot
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type dbgDB interface {
+	Query() string
+}
+
+type dbgPG struct{}
+
+func (*dbgPG) Query() string { return "pg" }
+
+type dbgCache struct{}
+
+type dbgRepo struct {
+	D     dbgDB           `ctxboot:"inject"`
+	All   []dbgDB         `ctxboot:"inject"`
+	Cache *dbgCache       `ctxboot:"inject,optional"`
+	Later Lazy[*dbgPG]    `ctxboot:"inject"`
+	Each  Provider[dbgDB] `ctxboot:"inject"`
+}
+
+// serveDebug serves a GET request for target from the debug handler of c
+func serveDebug(c *CtxbootComponentContext, target string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	c.DebugHandler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+	return w
+}
+
+func newDebugContext(t *testing.T) *CtxbootComponentContext {
+	t.Helper()
+	c := NewCtxbootComponentContext()
+	mustRegister(t, c, &dbgPG{})
+	mustRegister(t, c, &dbgRepo{})
+	if err := c.InitializeComponents(); err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestDebugComponents(t *testing.T) {
+	w := serveDebug(newDebugContext(t), "/components")
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("GET /components = %d %s", w.Code, w.Header().Get("Content-Type"))
+	}
+	var components []debugComponent
+	if err := json.Unmarshal(w.Body.Bytes(), &components); err != nil {
+		t.Fatal(err)
+	}
+	if len(components) != 2 || components[1].ID != "*ctxboot.dbgRepo" {
+		t.Fatalf("components = %+v, want pg and repo", components)
+	}
+
+	// Interfaces are listed as the injected components
+	repo := components[1]
+	if repo.State != "initialized" || repo.Kind != NodeComponent || repo.Scope != "singleton" || repo.InitDuration == "" {
+		t.Errorf("repo = %+v, want an initialized singleton", repo)
+	}
+	want := []debugDependency{
+		{Field: "D", Component: "*ctxboot.dbgPG", Kind: NodeComponent},
+		{Field: "All", Component: "*ctxboot.dbgPG", Kind: NodeComponent, Collection: true},
+		{Field: "Cache", Component: "*ctxboot.dbgCache", Kind: NodeMissing, Optional: true},
+		{Field: "Later", Component: "*ctxboot.dbgPG", Kind: NodeComponent, Deferred: true},
+		{Field: "Each", Component: "*ctxboot.dbgPG", Kind: NodeComponent, Deferred: true},
+	}
+	if !reflect.DeepEqual(repo.Dependencies, want) {
+		t.Errorf("dependencies = %+v, want %+v", repo.Dependencies, want)
+	}
+}
+
+func TestDebugGraph(t *testing.T) {
+	c := newDebugContext(t)
+	tests := []struct {
+		target      string
+		contentType string
+		prefix      string
+	}{
+		{"/graph", "text/vnd.graphviz; charset=utf-8", "digraph ctxboot {"},
+		{"/graph?format=dot", "text/vnd.graphviz; charset=utf-8", "digraph ctxboot {"},
+		{"/graph?format=mermaid", "text/plain; charset=utf-8", "flowchart LR"},
+		{"/graph?format=json", "application/json", "{"},
+	}
+	for _, tt := range tests {
+		w := serveDebug(c, tt.target)
+		if w.Code != http.StatusOK || w.Header().Get("Content-Type") != tt.contentType || !strings.HasPrefix(w.Body.String(), tt.prefix) {
+			t.Errorf("GET %s = %d %s %q, want %s starting with %q", tt.target, w.Code, w.Header().Get("Content-Type"), w.Body.String(), tt.contentType, tt.prefix)
+		}
+	}
+
+	var g Graph
+	if err := json.Unmarshal(serveDebug(c, "/graph?format=json").Body.Bytes(), &g); err != nil || g.Version != GraphVersion {
+		t.Errorf("GET /graph?format=json = %+v, %v, want a graph", g, err)
+	}
+
+	if w := serveDebug(c, "/graph?format=svg"); w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), `unknown graph format "svg"`) {
+		t.Errorf("GET /graph?format=svg = %d %q, want 400", w.Code, w.Body.String())
+	}
+}
+
+func TestDebugTimings(t *testing.T) {
+	w := serveDebug(newDebugContext(t), "/timings")
+	var timings struct {
+		Total      string        `json:"total"`
+		Components []debugTiming `json:"components"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &timings); err != nil {
+		t.Fatal(err)
+	}
+	if w.Code != http.StatusOK || timings.Total == "" || len(timings.Components) != 2 {
+		t.Errorf("GET /timings = %d %+v, want both components", w.Code, timings)
+	}
+}
+
+func TestDebugHealthAndIndex(t *testing.T) {
+	c := newDebugContext(t)
+	if w := serveDebug(c, "/health"); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"status":"UP"`) {
+		t.Errorf("GET /health = %d %q, want UP", w.Code, w.Body.String())
+	}
+
+	w := serveDebug(c, "/")
+	var index struct {
+		State     string   `json:"state"`
+		Endpoints []string `json:"endpoints"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &index); err != nil || index.State != "initialized" || len(index.Endpoints) != 4 {
+		t.Errorf("GET / = %q, %v, want the state and endpoints", w.Body.String(), err)
+	}
+
+	if w := serveDebug(c, "/nope"); w.Code != http.StatusNotFound {
+		t.Errorf("GET /nope = %d, want 404", w.Code)
+	}
+}
@@ -0,0 +1,161 @@
+package ctxboot
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// decorator is a function wrapping the components injected as an interface,
+// see AddDecorator
+type decorator struct {
+	fn    reflect.Value
+	iface reflect.Type
+	order int
+}
+
+// decoratedKey identifies a component decorated for an interface
+type decoratedKey struct {
+	key   componentKey
+	iface reflect.Type
+}
+
+// decoration is a decorated component, along with the instance it decorates
+type decoration struct {
+	instance  interface{}
+	decorated interface{}
+}
+
+// AddDecorator registers a function wrapping every component injected or
+// retrieved as an interface with cross-cutting behavior, e.g.
+//
+//	func Timed(next Database) Database
+//
+// fn takes and returns the interface, optionally followed by an error. It is
+// applied once per component, the decorated component being shared by all of
+// its injection points; components injected as their own type are not
+// decorated. Decorators of an interface are applied in ascending order, ties
+// in registration order, so the one with the highest order is the outermost.
+// Decorators of parent contexts apply to the components of their children
+func (c *CtxbootComponentContext) AddDecorator(fn interface{}, order int) error {
+	if fn == nil {
+		return errors.New("cannot store nil decorator")
+	}
+
+	fnVal := reflect.ValueOf(fn)
+	fnType := fnVal.Type()
+	if fnType.Kind() != reflect.Func || fnType.NumIn() != 1 || fnType.IsVariadic() {
+		return fmt.Errorf("decorator must be a function taking the component to decorate: %v", fnType)
+	}
+	iface := fnType.In(0)
+	if iface.Kind() != reflect.Interface {
+		return fmt.Errorf("decorator must decorate an interface: %v", fnType)
+	}
+	if fnType.NumOut() == 0 || fnType.NumOut() > 2 || fnType.Out(0) != iface || (fnType.NumOut() == 2 && fnType.Out(1) != errorType) {
+		return fmt.Errorf("decorator must return the decorated component, optionally followed by an error: %v", fnType)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.state != StateRegistering {
+		return newError(ErrContextSealed, componentKey{typ: iface}, "context is %v", c.state)
+	}
+	c.decorators = append(c.decorators, decorator{fn: fnVal, iface: iface, order: order})
+	return nil
+}
+
+// decoratorsOf returns the decorators of iface registered in c and its
+// ancestors, in the order they are applied
+func (c *CtxbootComponentContext) decoratorsOf(iface reflect.Type) []decorator {
+	var chain []*CtxbootComponentContext
+	for ctx := c; ctx != nil; ctx = ctx.parent {
+		chain = append([]*CtxbootComponentContext{ctx}, chain...)
+	}
+
+	// Ancestors first, for ties
+	var decorators []decorator
+	for _, ctx := range chain {
+		ctx.mu.RLock()
+		for _, d := range ctx.decorators {
+			if d.iface == iface {
+				decorators = append(decorators, d)
+			}
+		}
+		ctx.mu.RUnlock()
+	}
+	sort.SliceStable(decorators, func(i, j int) bool {
+		return decorators[i].order < decorators[j].order
+	})
+	return decorators
+}
+
+// decorate returns the component registered under key, instance, decorated
+// for the requested type typ. Singletons are decorated once per instance,
+// prototypes on every creation. Decorators run without holding c.mu, so they
+// may resolve other components; concurrent requests for a singleton being
+// decorated wait for it
+func (c *CtxbootComponentContext) decorate(key componentKey, typ reflect.Type, instance interface{}) (interface{}, error) {
+	if typ.Kind() != reflect.Interface {
+		return instance, nil
+	}
+	decorators := c.decoratorsOf(typ)
+	if len(decorators) == 0 {
+		return instance, nil
+	}
+	if c.isPrototype(key) {
+		return applyDecorators(key, decorators, instance)
+	}
+
+	dkey := decoratedKey{key: key, iface: typ}
+	for {
+		c.mu.Lock()
+		// Components replaced since are decorated again
+		if cached, ok := c.decorated[dkey]; ok && sameInstance(cached.instance, instance) {
+			c.mu.Unlock()
+			return cached.decorated, nil
+		}
+		if done, ok := c.decorating[dkey]; ok {
+			c.mu.Unlock()
+			<-done
+			continue
+		}
+		if c.decorating == nil {
+			c.decorating = make(map[decoratedKey]chan struct{})
+		}
+		done := make(chan struct{})
+		c.decorating[dkey] = done
+		c.mu.Unlock()
+
+		decorated, err := applyDecorators(key, decorators, instance)
+
+		c.mu.Lock()
+		if err == nil {
+			if c.decorated == nil {
+				c.decorated = make(map[decoratedKey]decoration)
+			}
+			c.decorated[dkey] = decoration{instance: instance, decorated: decorated}
+		}
+		delete(c.decorating, dkey)
+		close(done)
+		c.mu.Unlock()
+		return decorated, err
+	}
+}
+
+// applyDecorators wraps instance, the component registered under key, with
+// decorators in order
+func applyDecorators(key componentKey, decorators []decorator, instance interface{}) (interface{}, error) {
+	decorated := reflect.ValueOf(instance)
+	for _, d := range decorators {
+		results := d.fn.Call([]reflect.Value{decorated})
+		if len(results) == 2 && !results[1].IsNil() {
+			return nil, fmt.Errorf("failed to decorate component %v: %s: %w", key, funcName(d.fn), results[1].Interface().(error))
+		}
+		if results[0].IsNil() {
+			return nil, fmt.Errorf("failed to decorate component %v: %s returned nil", key, funcName(d.fn))
+		}
+		decorated = results[0]
+	}
+	return decorated.Interface(), nil
+}
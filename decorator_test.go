@@ -0,0 +1,235 @@
+package ctxboot
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type decGreeter interface {
+	Greet() string
+}
+
+type decNamer interface {
+	Name() string
+}
+
+type decHello struct {
+	name string
+}
+
+func (h *decHello) Greet() string { return "hello " + h.name }
+
+type decBob struct{}
+
+func (*decBob) Name() string { return "bob" }
+
+type decWrapper struct {
+	tag  string
+	next decGreeter
+}
+
+func (w *decWrapper) Greet() string { return w.tag + "(" + w.next.Greet() + ")" }
+
+type decConsumer struct {
+	Greeter decGreeter `ctxboot:"inject"`
+	Hello   *decHello  `ctxboot:"inject"`
+}
+
+// wrapper returns a decorator tagging greetings, counting its calls
+func wrapper(tag string, calls *atomic.Int64) func(decGreeter) decGreeter {
+	return func(next decGreeter) decGreeter {
+		if calls != nil {
+			calls.Add(1)
+		}
+		return &decWrapper{tag: tag, next: next}
+	}
+}
+
+func mustDecorate(t *testing.T, c *CtxbootComponentContext, fn interface{}, order int) {
+	t.Helper()
+	if err := c.AddDecorator(fn, order); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDecoratorOrder(t *testing.T) {
+	var calls atomic.Int64
+	c := NewCtxbootComponentContext()
+	mustDecorate(t, c, wrapper("b", &calls), 1)
+	mustDecorate(t, c, wrapper("a", nil), 0)
+	mustDecorate(t, c, wrapper("c", nil), 1)
+	hello := &decHello{name: "bob"}
+	consumer := &decConsumer{}
+	mustRegister(t, c, hello)
+	mustRegister(t, c, consumer)
+	if err := c.InitializeComponents(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := consumer.Greeter.Greet(), "c(b(a(hello bob)))"; got != want {
+		t.Errorf("Greet() = %q, want %q", got, want)
+	}
+
+	// Components injected as their own type are not decorated, and the
+	// decorated component is shared
+	if consumer.Hello != hello {
+		t.Errorf("Hello = %v, want the undecorated component", consumer.Hello)
+	}
+	if got := MustGet[decGreeter](c); got != consumer.Greeter || calls.Load() != 1 {
+		t.Errorf("Get() = %v after %d decorations, want the injected component decorated once", got, calls.Load())
+	}
+}
+
+func TestDecoratorErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		decorator interface{}
+		want      string
+	}{
+		{"error", func(next decGreeter) (decGreeter, error) { return nil, errors.New("no greeting") }, "no greeting"},
+		{"nil", func(next decGreeter) decGreeter { return nil }, "returned nil"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewCtxbootComponentContext()
+			mustDecorate(t, c, tt.decorator, 0)
+			mustRegister(t, c, &decHello{})
+			mustRegister(t, c, &decConsumer{})
+
+			err := c.InitializeComponents()
+			if err == nil || !strings.Contains(err.Error(), "failed to decorate component *ctxboot.decHello") || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("InitializeComponents() = %v, want the decoration failure", err)
+			}
+		})
+	}
+
+	c := NewCtxbootComponentContext()
+	for _, fn := range []interface{}{
+		func(next *decHello) *decHello { return next },
+		func(next decGreeter) decNamer { return nil },
+		func(next decGreeter) (decGreeter, string) { return next, "" },
+		"not a function",
+	} {
+		if err := c.AddDecorator(fn, 0); err == nil {
+			t.Errorf("AddDecorator(%T) succeeded, want an error", fn)
+		}
+	}
+}
+
+func TestDecoratorReplaced(t *testing.T) {
+	var calls atomic.Int64
+	c := NewCtxbootComponentContext()
+	mustDecorate(t, c, wrapper("w", &calls), 0)
+	mustRegister(t, c, &decHello{name: "ann"})
+	first := MustGet[decGreeter](c)
+
+	mustRegister(t, c, &decHello{name: "bob"})
+	second := MustGet[decGreeter](c)
+	if got, want := second.Greet(), "w(hello bob)"; got != want || calls.Load() != 2 {
+		t.Errorf("Greet() = %q after %d decorations, want %q decorated again", got, calls.Load(), want)
+	}
+	if first == second {
+		t.Error("the replaced component was not decorated again")
+	}
+}
+
+func TestDecoratorPrototype(t *testing.T) {
+	var calls atomic.Int64
+	c := NewCtxbootComponentContext()
+	mustDecorate(t, c, wrapper("w", &calls), 0)
+	if err := c.SetPrototype(reflect.TypeOf(&decHello{}), func() interface{} { return &decHello{} }); err != nil {
+		t.Fatal(err)
+	}
+
+	first, second := MustGet[decGreeter](c), MustGet[decGreeter](c)
+	if first == second || calls.Load() != 2 {
+		t.Errorf("%d decorations, want every prototype instance decorated", calls.Load())
+	}
+}
+
+func TestDecoratorParent(t *testing.T) {
+	parent := NewCtxbootComponentContext()
+	mustDecorate(t, parent, wrapper("parent", nil), 0)
+	child := parent.NewChild()
+	mustDecorate(t, child, wrapper("child", nil), 0)
+	mustRegister(t, child, &decHello{name: "bob"})
+
+	// Ancestors first, for ties
+	if got, want := MustGet[decGreeter](child).Greet(), "child(parent(hello bob))"; got != want {
+		t.Errorf("Greet() = %q, want %q", got, want)
+	}
+}
+
+type decNamedConsumer struct {
+	Greeter decGreeter `ctxboot:"inject"`
+	Namer   decNamer   `ctxboot:"inject"`
+}
+
+func TestDecoratorResolvesDecorated(t *testing.T) {
+	for _, workers := range []int{1, 4} {
+		c := NewCtxbootComponentContext()
+		c.SetInitWorkers(workers)
+		mustDecorate(t, c, func(next decNamer) decNamer { return next }, 0)
+		mustDecorate(t, c, func(next decGreeter) (decGreeter, error) {
+			namer, err := Get[decNamer](c)
+			if err != nil {
+				return nil, err
+			}
+			return &decWrapper{tag: namer.Name(), next: next}, nil
+		}, 0)
+		consumer := &decNamedConsumer{}
+		mustRegister(t, c, &decHello{})
+		mustRegister(t, c, &decBob{})
+		mustRegister(t, c, consumer)
+
+		done := make(chan error, 1)
+		go func() { done <- c.InitializeComponents() }()
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Fatalf("workers=%d: InitializeComponents() = %v", workers, err)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("workers=%d: InitializeComponents() did not return", workers)
+		}
+		if got, want := consumer.Greeter.Greet(), "bob(hello )"; got != want {
+			t.Errorf("workers=%d: Greet() = %q, want %q", workers, got, want)
+		}
+	}
+}
+
+func TestDecoratorConcurrent(t *testing.T) {
+	var calls atomic.Int64
+	c := NewCtxbootComponentContext()
+	mustDecorate(t, c, func(next decGreeter) decGreeter {
+		calls.Add(1)
+		time.Sleep(10 * time.Millisecond)
+		return &decWrapper{tag: "w", next: next}
+	}, 0)
+	mustRegister(t, c, &decHello{})
+
+	var wg sync.WaitGroup
+	results := make([]decGreeter, 8)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i] = MustGet[decGreeter](c)
+		}(i)
+	}
+	wg.Wait()
+
+	for _, result := range results {
+		if result != results[0] {
+			t.Fatal("concurrent lookups got different decorated components")
+		}
+	}
+	if calls.Load() != 1 {
+		t.Errorf("%d decorations, want 1", calls.Load())
+	}
+}
@@ -0,0 +1,92 @@
+package ctxboot
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Sentinel errors reported by the context, to be tested with errors.Is
+var (
+	// ErrComponentNotFound is reported when nothing provides a requested type
+	ErrComponentNotFound = errors.New("component not found")
+	// ErrAmbiguousComponent is reported when several components satisfy a
+	// requested type and none of them is primary
+	ErrAmbiguousComponent = errors.New("ambiguous component")
+	// ErrCircularDependency is reported when components depend on each other
+	ErrCircularDependency = errors.New("circular dependency")
+	// ErrNotAssignable is reported when a component does not fit the type it
+	// is registered or requested as
+	ErrNotAssignable = errors.New("not assignable")
+	// ErrMissingProperty is reported when no property source defines the
+	// property of a value field that has no default
+	ErrMissingProperty = errors.New("missing property")
+	// ErrContextSealed is reported for registrations once the context is
+	// initialized, see EnableUnsafeReinjection
+	ErrContextSealed = errors.New("context sealed")
+)
+
+// Error is the error reported by the context for a failed registration or
+// resolution. It unwraps to one of the Err sentinels, and can be retrieved
+// with errors.As to inspect the resolution path
+type Error struct {
+	Err    error    // ErrComponentNotFound, ErrAmbiguousComponent, ...
+	Path   []string // resolution path, e.g. [repository.UserRepository.db database.Database]
+	Detail string   // additional information such as the ambiguous candidates
+}
+
+// Error returns the sentinel message followed by the resolution path
+func (e *Error) Error() string {
+	var b strings.Builder
+	b.WriteString(e.Err.Error())
+	if len(e.Path) > 0 {
+		b.WriteString(": ")
+		b.WriteString(strings.Join(e.Path, " -> "))
+	}
+	if e.Detail != "" {
+		b.WriteString(" (")
+		b.WriteString(e.Detail)
+		b.WriteString(")")
+	}
+	return b.String()
+}
+
+// Unwrap returns the sentinel error
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// newError creates an *Error for the component registered or requested as key
+func newError(sentinel error, key componentKey, format string, args ...interface{}) *Error {
+	return &Error{
+		Err:    sentinel,
+		Path:   []string{key.String()},
+		Detail: fmt.Sprintf(format, args...),
+	}
+}
+
+// withPath prepends a step to the resolution path of err when it is an
+// *Error, and otherwise wraps err with the step
+func withPath(err error, step string) error {
+	if e, ok := err.(*Error); ok {
+		e.Path = append([]string{step}, e.Path...)
+		return e
+	}
+	return fmt.Errorf("%s: %w", step, err)
+}
+
+// isMissing reports whether err is an ErrComponentNotFound for the requested
+// type itself, as opposed to one of the dependencies of the component found
+func isMissing(err error) bool {
+	e, ok := err.(*Error)
+	return ok && e.Err == ErrComponentNotFound && len(e.Path) == 1
+}
+
+// fieldStep names a field in a resolution path, e.g. repository.UserRepository.db
+func fieldStep(owner reflect.Type, field string) string {
+	if owner.Kind() == reflect.Ptr {
+		owner = owner.Elem()
+	}
+	return owner.String() + "." + field
+}
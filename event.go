@@ -0,0 +1,244 @@
+package ctxboot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"reflect"
+	"sync"
+)
+
+// Publisher publishes events to the components listening for them. A field
+// of type Publisher is injected with the event bus of the context, without
+// registering anything:
+//
+//	//ctxboot:component
+//	type SignupService struct {
+//	    Events ctxboot.Publisher `ctxboot:"inject"`
+//	}
+//
+// A component listens for the events assignable to E by implementing
+//
+//	OnEvent(ctx context.Context, event E) error
+//
+// or the same method without result. Components are subscribed once
+// InitializeComponents initialized them, in initialization order, and
+// unsubscribed by Shutdown. Events published in a child context also reach
+// the listeners of its parent, but not the other way around
+type Publisher interface {
+	// Publish delivers event to its listeners one after the other, in the
+	// calling goroutine, and returns their errors
+	Publish(ctx context.Context, event interface{}) error
+	// PublishAsync delivers event to each of its listeners in a goroutine of
+	// its own and returns at once. Failures are passed to the handler set
+	// with SetEventErrorHandler, and Shutdown waits for pending deliveries
+	PublishAsync(ctx context.Context, event interface{})
+}
+
+// ContextInitialized is published by a context once InitializeComponents
+// initialized all of its components and sealed the context. Listener
+// failures are passed to the handler set with SetEventErrorHandler, the
+// context staying initialized
+type ContextInitialized struct{}
+
+// ContextShuttingDown is published by a context when Shutdown starts, before
+// any of its components is stopped. Listener failures are returned by
+// Shutdown
+type ContextShuttingDown struct{}
+
+var (
+	publisherType = reflect.TypeOf((*Publisher)(nil)).Elem()
+	contextType   = reflect.TypeOf((*context.Context)(nil)).Elem()
+)
+
+// listener is a component subscribed to an event type
+type listener struct {
+	key    componentKey
+	method reflect.Value // OnEvent
+	event  reflect.Type
+}
+
+// eventBus is the Publisher of a context
+type eventBus struct {
+	parent    *eventBus
+	mu        sync.RWMutex
+	listeners []listener
+	onError   func(event interface{}, err error)
+	closed    bool           // set by wait, no more asynchronous deliveries
+	pending   sync.WaitGroup // asynchronous deliveries
+}
+
+// newEventBus creates the event bus of a context, forwarding events to the
+// bus of its parent, if any
+func newEventBus(parent *eventBus) *eventBus {
+	return &eventBus{parent: parent}
+}
+
+// Publish implements Publisher
+func (b *eventBus) Publish(ctx context.Context, event interface{}) error {
+	return b.publish(ctx, event, true)
+}
+
+// PublishAsync implements Publisher. Events published once Shutdown started
+// waiting for pending deliveries are not delivered, and reported as failures
+func (b *eventBus) PublishAsync(ctx context.Context, event interface{}) {
+	for bus := b; bus != nil; bus = bus.parent {
+		// Deliveries are added under b.mu, so that none is added once wait
+		// closed the bus and waits for them
+		bus.mu.RLock()
+		closed := bus.closed
+		var listeners []listener
+		if !closed {
+			listeners = bus.listenersLocked(event)
+			bus.pending.Add(len(listeners))
+		}
+		bus.mu.RUnlock()
+		if closed {
+			bus.handleError(event, fmt.Errorf("event %T not delivered: context is shutting down", event))
+			continue
+		}
+
+		for _, l := range listeners {
+			go func(bus *eventBus, l listener) {
+				defer bus.pending.Done()
+				if err := l.deliver(ctx, event); err != nil {
+					bus.handleError(event, err)
+				}
+			}(bus, l)
+		}
+	}
+}
+
+// publish delivers event synchronously to the listeners of b and, if
+// forward is set, to those of its ancestors
+func (b *eventBus) publish(ctx context.Context, event interface{}, forward bool) error {
+	if event == nil {
+		return errors.New("cannot publish nil event")
+	}
+
+	var errs []error
+	for bus := b; bus != nil; bus = bus.parent {
+		for _, l := range bus.listenersOf(event) {
+			if err := l.deliver(ctx, event); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		if !forward {
+			break
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// listenersOf returns the listeners of b for event
+func (b *eventBus) listenersOf(event interface{}) []listener {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.listenersLocked(event)
+}
+
+// listenersLocked is listenersOf for callers holding b.mu
+func (b *eventBus) listenersLocked(event interface{}) []listener {
+	eventType := reflect.TypeOf(event)
+	if eventType == nil {
+		return nil
+	}
+
+	var listeners []listener
+	for _, l := range b.listeners {
+		if eventType.AssignableTo(l.event) {
+			listeners = append(listeners, l)
+		}
+	}
+	return listeners
+}
+
+// deliver calls the OnEvent method of the listener
+func (l listener) deliver(ctx context.Context, event interface{}) error {
+	results := l.method.Call([]reflect.Value{reflect.ValueOf(ctx), reflect.ValueOf(event)})
+	if len(results) == 1 && !results[0].IsNil() {
+		return fmt.Errorf("listener %v failed on %T: %w", l.key, event, results[0].Interface().(error))
+	}
+	return nil
+}
+
+// subscribe subscribes the component registered under key if it has an
+// OnEvent method, replacing any previous subscription of key
+func (b *eventBus) subscribe(key componentKey, instance interface{}) {
+	method := reflect.ValueOf(instance).MethodByName("OnEvent")
+	if !method.IsValid() {
+		return
+	}
+	methodType := method.Type()
+	if methodType.NumIn() != 2 || methodType.In(0) != contextType || methodType.IsVariadic() ||
+		methodType.NumOut() > 1 || (methodType.NumOut() == 1 && methodType.Out(0) != errorType) {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	l := listener{key: key, method: method, event: methodType.In(1)}
+	for i := range b.listeners {
+		if b.listeners[i].key == key {
+			b.listeners[i] = l
+			return
+		}
+	}
+	b.listeners = append(b.listeners, l)
+}
+
+// unsubscribeAll removes the listeners of b
+func (b *eventBus) unsubscribeAll() {
+	b.mu.Lock()
+	b.listeners = nil
+	b.mu.Unlock()
+}
+
+// wait closes b to asynchronous deliveries and waits for the pending ones
+// until ctx is done
+func (b *eventBus) wait(ctx context.Context) error {
+	b.mu.Lock()
+	b.closed = true
+	b.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		b.pending.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("pending events not delivered: %w", ctx.Err())
+	}
+}
+
+// handleError reports a failure that cannot be returned to the publisher
+func (b *eventBus) handleError(event interface{}, err error) {
+	b.mu.RLock()
+	onError := b.onError
+	b.mu.RUnlock()
+	if onError == nil {
+		log.Printf("ctxboot: %v", err)
+		return
+	}
+	onError(event, err)
+}
+
+// Publisher returns the event bus of c, see Publisher
+func (c *CtxbootComponentContext) Publisher() Publisher {
+	return c.events
+}
+
+// SetEventErrorHandler sets the function called when a listener fails on an
+// event published with PublishAsync or on ContextInitialized, or when an
+// event is published asynchronously during Shutdown. By default failures are
+// logged with the standard logger
+func (c *CtxbootComponentContext) SetEventErrorHandler(handler func(event interface{}, err error)) {
+	c.events.mu.Lock()
+	c.events.onError = handler
+	c.events.mu.Unlock()
+}
@@ -0,0 +1,110 @@
+package ctxboot
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+type initListener struct {
+	c           *CtxbootComponentContext
+	initialized bool
+	fail        bool
+}
+
+func (l *initListener) OnEvent(ctx context.Context, e ContextInitialized) error {
+	l.initialized = l.c.IsInitialized()
+	if l.fail {
+		return errors.New("listener failed")
+	}
+	return nil
+}
+
+func TestContextInitializedAfterSealing(t *testing.T) {
+	c := NewCtxbootComponentContext()
+	listener := &initListener{c: c, fail: true}
+	mustRegister(t, c, listener)
+	var handled []error
+	c.SetEventErrorHandler(func(event interface{}, err error) {
+		handled = append(handled, err)
+	})
+
+	if err := c.InitializeComponents(); err != nil {
+		t.Fatalf("InitializeComponents() = %v, want the listener failure handled", err)
+	}
+	if !listener.initialized {
+		t.Error("the listener saw an unsealed context")
+	}
+	if !c.IsInitialized() {
+		t.Errorf("State() = %v, want initialized", c.State())
+	}
+	if len(handled) != 1 || !strings.Contains(handled[0].Error(), "listener failed") {
+		t.Errorf("handled errors = %v, want the listener failure", handled)
+	}
+}
+
+type countingListener struct {
+	delivered atomic.Int64
+}
+
+func (l *countingListener) OnEvent(ctx context.Context, e string) {
+	l.delivered.Add(1)
+}
+
+func TestPublishAsyncDuringShutdown(t *testing.T) {
+	c := NewCtxbootComponentContext()
+	listener := &countingListener{}
+	mustRegister(t, c, listener)
+	var undelivered atomic.Int64
+	c.SetEventErrorHandler(func(event interface{}, err error) {
+		if strings.Contains(err.Error(), "shutting down") {
+			undelivered.Add(1)
+		}
+	})
+	if err := c.InitializeComponents(); err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	var published atomic.Int64
+	stop := make(chan struct{})
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+					c.Publisher().PublishAsync(context.Background(), "event")
+					published.Add(1)
+				}
+			}
+		}()
+	}
+
+	if err := c.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	delivered := listener.delivered.Load()
+	close(stop)
+	wg.Wait()
+
+	// Nothing is delivered once Shutdown returned, and events are either
+	// delivered or reported
+	if got := listener.delivered.Load(); got != delivered {
+		t.Errorf("%d events delivered after Shutdown", got-delivered)
+	}
+	if got, want := delivered+undelivered.Load(), published.Load(); got < want {
+		t.Errorf("%d events delivered or reported, want %d", got, want)
+	}
+
+	c.Publisher().PublishAsync(context.Background(), "late")
+	if listener.delivered.Load() != delivered {
+		t.Error("an event published after Shutdown was delivered")
+	}
+}
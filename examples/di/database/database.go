@@ -6,9 +6,9 @@ type Database interface {
 	GetConnectionString() string
 }
 
-// DatabaseImpl handles database operations
+// DatabaseImpl handles database operations in memory, for the dev profile
 //
-//ctxboot:component
+//ctxboot:component profile=dev
 type DatabaseImpl struct {
 	ConnectionString string
 }
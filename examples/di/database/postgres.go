@@ -1,16 +1,17 @@
 package database
 
-// // PostgresDatabase handles PostgreSQL database operations
-// //
-// //ctxboot:component
-// type PostgresDatabase struct {
-// 	ConnectionString string
-// }
+// PostgresDatabase handles PostgreSQL database operations. It implements
+// Database in every profile but dev
+//
+//ctxboot:component profile=!dev
+type PostgresDatabase struct {
+	ConnectionString string
+}
 
-// func (db *PostgresDatabase) Connect() {
-// 	db.ConnectionString = "postgres://connected"
-// }
+func (db *PostgresDatabase) Connect() {
+	db.ConnectionString = "postgres://connected"
+}
 
-// func (db *PostgresDatabase) GetConnectionString() string {
-// 	return db.ConnectionString
-// }
+func (db *PostgresDatabase) GetConnectionString() string {
+	return db.ConnectionString
+}
@@ -2,7 +2,9 @@ package main
 
 import (
 	"fmt"
+	"os"
 
+	"github.com/iondodon/ctxboot"
 	"github.com/iondodon/ctxboot/examples/di/repository"
 )
 
@@ -18,8 +20,9 @@ func (s *UserService) GetUser(id string) string {
 }
 
 func main() {
-	// Create a new context
-	cc := NewComponentContext()
+	// Create a new context, activating profiles with --ctxboot.profiles.active=dev
+	// or CTXBOOT_PROFILES_ACTIVE=dev
+	cc := NewComponentContextWith(ctxboot.NewProperties(ctxboot.EnvSource(), ctxboot.ArgsSource(os.Args[1:])))
 
 	// Initialize components and their dependencies
 	if err := cc.InitializeComponents(); err != nil {
@@ -23,6 +23,19 @@ func (c *ComponentContext) RegisterComponent(instance interface{}) error {
 	return c.SetComponent(reflect.TypeOf(instance), instance)
 }
 
+// RegisterNamed registers a component instance under a qualifier name
+func (c *ComponentContext) RegisterNamed(name string, instance interface{}) error {
+	if instance == nil {
+		return fmt.Errorf("cannot register nil component")
+	}
+	return c.SetNamedComponent(name, reflect.TypeOf(instance), instance)
+}
+
+// NewChild creates a child context that overrides components of c and falls back to c for the others
+func (c *ComponentContext) NewChild() *ComponentContext {
+	return &ComponentContext{c.CtxbootComponentContext.NewChild()}
+}
+
 // registerScanedComponenets registers all components
 func (c *ComponentContext) registerScanedComponenets() error {
 	// Register components in dependency order
@@ -32,19 +45,36 @@ func (c *ComponentContext) registerScanedComponenets() error {
 		log.Fatalf("Failed to register component %s: %v", "EnglishGreeter", err)
 	}
 	
-	
 	return nil
 }
 
 // NewComponentContext creates a new component context instance and registers all scanned components
 func NewComponentContext() *ComponentContext {
+	return NewComponentContextWith(nil)
+}
+
+// NewComponentContextWith is like NewComponentContext, the conditions of the scanned components being
+// evaluated against the given properties and profiles (by default those listed by ctxboot.ProfilesProperty)
+func NewComponentContextWith(properties *ctxboot.Properties, profiles ...string) *ComponentContext {
 	ctx := &ComponentContext{ctxboot.NewCtxbootComponentContext()}
+	if properties != nil {
+		ctx.SetProperties(properties)
+	}
+	if len(profiles) > 0 {
+		ctx.SetProfiles(profiles...)
+	}
 	if err := ctx.registerScanedComponenets(); err != nil {
 		log.Fatalf("Failed to register scanned components: %v", err)
 	}
 	return ctx
 }
 
+// ValidateComponentContext registers all scanned components and checks their wiring without creating,
+// injecting or initializing any of them, reporting all the problems found, e.g. in a CI check
+func ValidateComponentContext() error {
+	return NewComponentContext().Validate()
+}
+
 // Component getter methods
 
 // GetEnglishGreeter returns the EnglishGreeter component
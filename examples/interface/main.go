@@ -2,7 +2,8 @@ package main
 
 import (
 	"fmt"
-	"reflect"
+
+	"github.com/iondodon/ctxboot"
 )
 
 // Define an interface
@@ -29,13 +30,12 @@ func main() {
 	}
 
 	// Example 1: Get component by interface type
-	greeter, err := cc.GetComponent(reflect.TypeOf((*Greeter)(nil)).Elem())
+	greeter, err := ctxboot.Get[Greeter](cc)
 	if err != nil {
 		panic(err)
 	}
-	g := greeter.(Greeter)
 	fmt.Println("Example 1 - Get by interface:")
-	fmt.Println(g.Greet())
+	fmt.Println(greeter.Greet())
 
 	// Example 2: Get component using generated getter method
 	englishGreeter, err := cc.GetEnglishGreeter()
@@ -0,0 +1,66 @@
+package ctxboot
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// Container is implemented by CtxbootComponentContext and by any type
+// embedding it, such as the generated ComponentContext
+type Container interface {
+	componentContext() *CtxbootComponentContext
+}
+
+// componentContext implements Container
+func (c *CtxbootComponentContext) componentContext() *CtxbootComponentContext {
+	return c
+}
+
+// Get retrieves the component assignable to T, which may be a struct pointer,
+// an interface, or a slice or map of them:
+//
+//	service, err := ctxboot.Get[*UserService](cc)
+//	greeter, err := ctxboot.Get[Greeter](cc)
+func Get[T any](c Container) (T, error) {
+	return GetNamed[T](c, "")
+}
+
+// GetNamed is like Get for a component registered under a qualifier name
+func GetNamed[T any](c Container, name string) (T, error) {
+	var zero T
+	value, err := c.componentContext().fieldValue(typeOf[T](), injectTag{name: name})
+	if err != nil {
+		return zero, err
+	}
+	return value.Interface().(T), nil
+}
+
+// MustGet is like Get but panics if the component cannot be retrieved
+func MustGet[T any](c Container) T {
+	component, err := Get[T](c)
+	if err != nil {
+		panic(fmt.Sprintf("ctxboot: %v", err))
+	}
+	return component
+}
+
+// Register stores v as the component for T, e.g. Register[Greeter](cc, g)
+// registers g under the Greeter interface
+func Register[T any](c Container, v T) error {
+	return RegisterNamed[T](c, "", v)
+}
+
+// RegisterNamed is like Register for a component registered under a
+// qualifier name
+func RegisterNamed[T any](c Container, name string, v T) error {
+	if val := reflect.ValueOf(&v).Elem(); (val.Kind() == reflect.Ptr || val.Kind() == reflect.Interface) && val.IsNil() {
+		return errors.New("cannot store nil component")
+	}
+	return c.componentContext().SetNamedComponent(name, lookupType(typeOf[T]()), v)
+}
+
+// typeOf returns the reflect.Type of T, interfaces included
+func typeOf[T any]() reflect.Type {
+	return reflect.TypeOf((*T)(nil)).Elem()
+}
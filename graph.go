@@ -0,0 +1,397 @@
+package ctxboot
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// GraphVersion is the version of the JSON schema of Graph
+const GraphVersion = 1
+
+// Graph node kinds
+const (
+	NodeComponent = "component" // registered instance or prototype
+	NodeProvider  = "provider"  // component created by a provider function
+	NodeInterface = "interface" // interface requested by injection points
+	NodeMissing   = "missing"   // requested type nothing provides
+)
+
+// Graph edge kinds
+const (
+	EdgeInject     = "inject"     // from a component to what one of its injection points receives
+	EdgeImplements = "implements" // from an interface to a component implementing it
+)
+
+// Graph is the dependency graph of a context, see Graph, or of the sources
+// scanned by the generator. Its writers sort nodes and edges, so the export
+// of unchanged wiring does not change
+type Graph struct {
+	Version int         `json:"version"`
+	Nodes   []GraphNode `json:"nodes"`
+	Edges   []GraphEdge `json:"edges"`
+}
+
+// GraphNode is a component or an interface of a Graph
+type GraphNode struct {
+	ID        string `json:"id"`                  // type and qualifier, e.g. *database.Pool(replica)
+	Kind      string `json:"kind"`                // NodeComponent, NodeProvider, ...
+	Type      string `json:"type"`                // e.g. *database.Pool
+	Package   string `json:"package,omitempty"`   // import path of the package declaring the type
+	Qualifier string `json:"qualifier,omitempty"` // name the component is registered under
+	Scope     string `json:"scope,omitempty"`     // singleton or prototype
+	Primary   bool   `json:"primary,omitempty"`
+}
+
+// GraphEdge is a dependency of a Graph
+type GraphEdge struct {
+	From       string `json:"from"`
+	To         string `json:"to"`
+	Kind       string `json:"kind"`                // EdgeInject or EdgeImplements
+	Field      string `json:"field,omitempty"`     // injected field, or provider parameter as #i
+	Qualifier  string `json:"qualifier,omitempty"` // name requested by the injection point
+	Collection bool   `json:"collection,omitempty"`
+	Optional   bool   `json:"optional,omitempty"`
+	Deferred   bool   `json:"deferred,omitempty"` // Lazy or Provider field
+}
+
+// AddNode adds a node, unless one with the same ID was added
+func (g *Graph) AddNode(node GraphNode) {
+	for _, n := range g.Nodes {
+		if n.ID == node.ID {
+			return
+		}
+	}
+	g.Nodes = append(g.Nodes, node)
+}
+
+// AddEdge adds an edge, unless the same edge was added
+func (g *Graph) AddEdge(edge GraphEdge) {
+	for _, e := range g.Edges {
+		if e == edge {
+			return
+		}
+	}
+	g.Edges = append(g.Edges, edge)
+}
+
+// sort sorts nodes by ID and edges by their ends, kind and field
+func (g *Graph) sort() {
+	sort.Slice(g.Nodes, func(i, j int) bool {
+		return g.Nodes[i].ID < g.Nodes[j].ID
+	})
+	sort.Slice(g.Edges, func(i, j int) bool {
+		a, b := g.Edges[i], g.Edges[j]
+		if a.From != b.From {
+			return a.From < b.From
+		}
+		if a.To != b.To {
+			return a.To < b.To
+		}
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		return a.Field < b.Field
+	})
+}
+
+// Write writes the graph in format, which is dot, mermaid or json
+func (g *Graph) Write(w io.Writer, format string) error {
+	switch format {
+	case "dot":
+		return g.WriteDOT(w)
+	case "mermaid":
+		return g.WriteMermaid(w)
+	case "json":
+		return g.WriteJSON(w)
+	}
+	return fmt.Errorf("unknown graph format %q, expected dot, mermaid or json", format)
+}
+
+// WriteJSON writes the graph as indented JSON
+func (g *Graph) WriteJSON(w io.Writer) error {
+	g.sort()
+	if g.Nodes == nil {
+		g.Nodes = []GraphNode{}
+	}
+	if g.Edges == nil {
+		g.Edges = []GraphEdge{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(g)
+}
+
+// WriteDOT writes the graph in the Graphviz DOT language. Interfaces are
+// drawn as ellipses, missing types in red and implements edges dashed
+func (g *Graph) WriteDOT(w io.Writer) error {
+	g.sort()
+
+	var b strings.Builder
+	b.WriteString("digraph ctxboot {\n\trankdir=LR;\n\tnode [shape=box];\n")
+	for _, n := range g.Nodes {
+		attrs := fmt.Sprintf("label=%q", strings.Join(n.label(), "\n"))
+		switch n.Kind {
+		case NodeInterface:
+			attrs += " shape=ellipse"
+		case NodeMissing:
+			attrs += " color=red"
+		}
+		if n.Primary {
+			attrs += " penwidth=2"
+		}
+		fmt.Fprintf(&b, "\t%q [%s];\n", n.ID, attrs)
+	}
+	for _, e := range g.Edges {
+		var attrs []string
+		if label := e.label(); label != "" {
+			attrs = append(attrs, fmt.Sprintf("label=%q", label))
+		}
+		if e.Kind == EdgeImplements {
+			attrs = append(attrs, "style=dashed")
+		} else if e.Optional || e.Deferred {
+			attrs = append(attrs, "style=dotted")
+		}
+		fmt.Fprintf(&b, "\t%q -> %q [%s];\n", e.From, e.To, strings.Join(attrs, " "))
+	}
+	b.WriteString("}\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteMermaid writes the graph as a Mermaid flowchart. Interfaces are drawn
+// as stadiums and implements edges dashed
+func (g *Graph) WriteMermaid(w io.Writer) error {
+	g.sort()
+
+	// Mermaid node IDs must be plain identifiers
+	ids := make(map[string]string, len(g.Nodes))
+	var b strings.Builder
+	b.WriteString("flowchart LR\n")
+	for i, n := range g.Nodes {
+		id := fmt.Sprintf("n%d", i)
+		ids[n.ID] = id
+		label := mermaidText(strings.Join(n.label(), "<br/>"))
+		if n.Kind == NodeInterface {
+			fmt.Fprintf(&b, "\t%s([\"%s\"])\n", id, label)
+		} else {
+			fmt.Fprintf(&b, "\t%s[\"%s\"]\n", id, label)
+		}
+	}
+	for _, e := range g.Edges {
+		arrow := "-->"
+		if e.Kind == EdgeImplements || e.Optional || e.Deferred {
+			arrow = "-.->"
+		}
+		if label := e.label(); label != "" {
+			arrow += "|\"" + mermaidText(label) + "\"|"
+		}
+		fmt.Fprintf(&b, "\t%s %s %s\n", ids[e.From], arrow, ids[e.To])
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// mermaidText escapes the quotes of a Mermaid label
+func mermaidText(s string) string {
+	return strings.ReplaceAll(s, `"`, "#quot;")
+}
+
+// label returns the lines describing a node in DOT and Mermaid
+func (n GraphNode) label() []string {
+	lines := []string{n.ID}
+	var notes []string
+	if n.Kind != NodeComponent {
+		notes = append(notes, n.Kind)
+	}
+	if n.Scope == "prototype" {
+		notes = append(notes, n.Scope)
+	}
+	if n.Primary {
+		notes = append(notes, "primary")
+	}
+	if len(notes) > 0 {
+		lines = append(lines, "«"+strings.Join(notes, ", ")+"»")
+	}
+	return lines
+}
+
+// label returns the text of an edge in DOT and Mermaid
+func (e GraphEdge) label() string {
+	if e.Kind == EdgeImplements {
+		return ""
+	}
+	label := e.Field
+	if e.Qualifier != "" {
+		label += " (" + e.Qualifier + ")"
+	}
+	switch {
+	case e.Collection:
+		label += " []"
+	case e.Deferred:
+		label += " lazy"
+	case e.Optional:
+		label += " ?"
+	}
+	return label
+}
+
+// Graph returns the dependency graph of the components registered in c and
+// its ancestors, following inject fields and provider parameters
+func (c *CtxbootComponentContext) Graph() *Graph {
+	g := &Graph{Version: GraphVersion}
+
+	// Collect the components, those of a child overriding its ancestors'
+	type registration struct {
+		key   componentKey
+		owner *CtxbootComponentContext
+	}
+	var registrations []registration
+	seen := make(map[componentKey]bool)
+	for ctx := c; ctx != nil; ctx = ctx.parent {
+		ctx.mu.RLock()
+		for _, key := range ctx.order {
+			if !seen[key] {
+				seen[key] = true
+				registrations = append(registrations, registration{key: key, owner: ctx})
+				g.AddNode(ctx.graphNode(key))
+			}
+		}
+		ctx.mu.RUnlock()
+	}
+
+	interfaces := make(map[string]reflect.Type)
+	for _, r := range registrations {
+		for _, edge := range r.owner.graphEdges(r.key) {
+			target := edge.target
+			switch {
+			case target.Kind() == reflect.Interface && edge.Qualifier == "":
+				interfaces[target.String()] = target
+				g.AddNode(GraphNode{ID: target.String(), Kind: NodeInterface, Type: target.String(), Package: target.PkgPath()})
+				edge.To = target.String()
+				g.AddEdge(edge.GraphEdge)
+			case edge.Collection:
+				for _, entry := range r.owner.collectionEntries(target) {
+					edge.To = entry.key.String()
+					g.AddEdge(edge.GraphEdge)
+				}
+			default:
+				keys := r.owner.locate(target, edge.Qualifier)
+				if len(keys) == 0 {
+					missing := componentKey{typ: target, name: edge.Qualifier}
+					g.AddNode(GraphNode{ID: missing.String(), Kind: NodeMissing, Type: target.String(), Package: typePackage(target), Qualifier: missing.name})
+					keys = append(keys, missing)
+				}
+				for _, key := range keys {
+					edge.To = key.String()
+					g.AddEdge(edge.GraphEdge)
+				}
+			}
+		}
+	}
+
+	// Link the requested interfaces to their implementations
+	for id, iface := range interfaces {
+		for _, r := range registrations {
+			if r.key.typ != iface && r.key.typ.Implements(iface) {
+				g.AddEdge(GraphEdge{From: id, To: r.key.String(), Kind: EdgeImplements})
+			}
+		}
+	}
+
+	g.sort()
+	return g
+}
+
+// graphNode describes the component registered under key. Caller must hold
+// c.mu
+func (c *CtxbootComponentContext) graphNode(key componentKey) GraphNode {
+	node := GraphNode{
+		ID:        key.String(),
+		Kind:      NodeComponent,
+		Type:      key.typ.String(),
+		Package:   typePackage(key.typ),
+		Qualifier: key.name,
+		Scope:     "singleton",
+		Primary:   c.primary[key],
+	}
+	if _, ok := c.providers[key]; ok {
+		node.Kind = NodeProvider
+	}
+	if _, ok := c.prototypes[key]; ok {
+		node.Scope = "prototype"
+	}
+	return node
+}
+
+// typePackage returns the import path of the package declaring typ or, for
+// a pointer, its element type
+func typePackage(typ reflect.Type) string {
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	return typ.PkgPath()
+}
+
+// graphEdge is an inject edge whose target is yet to be located
+type graphEdge struct {
+	GraphEdge
+	target reflect.Type // type the injection point is resolved from
+}
+
+// graphEdges returns the injection points of the component registered under
+// key as edges, see injectionPoints. Components whose injection points
+// cannot be walked, reported by InitializeComponents and Validate, have none
+func (c *CtxbootComponentContext) graphEdges(key componentKey) []graphEdge {
+	points, err := c.injectionPoints(key)
+	if err != nil {
+		return nil
+	}
+
+	var edges []graphEdge
+	for _, point := range points {
+		if point.valueType == publisherType {
+			continue
+		}
+		edge := graphEdge{GraphEdge: GraphEdge{
+			From:      key.String(),
+			Kind:      EdgeInject,
+			Field:     point.field,
+			Qualifier: point.opts.name,
+			Optional:  point.opts.optional,
+			Deferred:  point.deferred,
+		}}
+		valueType := point.valueType
+		if elemType, ok := collectionElem(valueType); ok {
+			edge.Collection = true
+			valueType = elemType
+		}
+		edge.target = lookupType(valueType)
+		edges = append(edges, edge)
+	}
+	return edges
+}
+
+// locate returns the key of the component satisfying typ and name in c or,
+// if it has none, in its closest ancestor that has one. All the candidates
+// are returned when they are ambiguous
+func (c *CtxbootComponentContext) locate(typ reflect.Type, name string) []componentKey {
+	for ctx := c; ctx != nil; ctx = ctx.parent {
+		ctx.mu.RLock()
+		candidates := ctx.candidates(typ, name)
+		key, err := ctx.resolve(typ, name)
+		ctx.mu.RUnlock()
+		if err == nil {
+			return []componentKey{key}
+		}
+		if len(candidates) > 0 {
+			return candidates
+		}
+	}
+	return nil
+}
@@ -0,0 +1,225 @@
+package ctxboot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// HealthChecker is implemented by components that can tell whether they are
+// healthy, e.g. a database pinging its server
+type HealthChecker interface {
+	HealthCheck(ctx context.Context) error
+}
+
+// HealthGroup is a group of health checks, see HealthGrouper
+type HealthGroup string
+
+const (
+	// Liveness checks tell whether the process must be restarted
+	Liveness HealthGroup = "liveness"
+	// Readiness checks tell whether the process can serve requests
+	Readiness HealthGroup = "readiness"
+)
+
+// HealthGrouper is implemented by health checkers that belong to other groups
+// than Readiness, the default
+type HealthGrouper interface {
+	HealthGroups() []HealthGroup
+}
+
+// HealthStatus is the status of a health check or report
+type HealthStatus string
+
+const (
+	// HealthUp is the status of a passing check or report
+	HealthUp HealthStatus = "UP"
+	// HealthDown is the status of a failing check or report
+	HealthDown HealthStatus = "DOWN"
+)
+
+// DefaultHealthCheckTimeout is how long a health check may take unless
+// SetHealthCheckTimeout is called
+const DefaultHealthCheckTimeout = 5 * time.Second
+
+// HealthReport aggregates the health checks of a context, see CheckHealth
+type HealthReport struct {
+	Status HealthStatus      `json:"status"`
+	State  string            `json:"state"` // see State
+	Checks []ComponentHealth `json:"checks"`
+}
+
+// ComponentHealth is the result of the health check of a component
+type ComponentHealth struct {
+	Component ComponentID
+	Groups    []HealthGroup
+	Status    HealthStatus
+	Duration  time.Duration
+	Err       error
+}
+
+// MarshalJSON encodes the result with the component as a string, the
+// duration as text, e.g. "1.2ms", and the error message, if any
+func (h ComponentHealth) MarshalJSON() ([]byte, error) {
+	result := struct {
+		Component string        `json:"component"`
+		Groups    []HealthGroup `json:"groups"`
+		Status    HealthStatus  `json:"status"`
+		Duration  string        `json:"duration"`
+		Error     string        `json:"error,omitempty"`
+	}{
+		Component: h.Component.String(),
+		Groups:    h.Groups,
+		Status:    h.Status,
+		Duration:  h.Duration.String(),
+	}
+	if h.Err != nil {
+		result.Error = h.Err.Error()
+	}
+	return json.Marshal(result)
+}
+
+// SetHealthCheckTimeout sets how long each health check may take before it is
+// reported as down, DefaultHealthCheckTimeout by default
+func (c *CtxbootComponentContext) SetHealthCheckTimeout(timeout time.Duration) {
+	c.mu.Lock()
+	c.healthTimeout = timeout
+	c.mu.Unlock()
+}
+
+// CheckHealth runs the health checks of group, or all of them if group is
+// empty, concurrently and aggregates them. The checked components are the
+// initialized components of c and its ancestors implementing HealthChecker.
+// The report is down if one of the checks is, or, unless group is Liveness,
+// if c is not initialized
+func (c *CtxbootComponentContext) CheckHealth(ctx context.Context, group HealthGroup) HealthReport {
+	c.mu.RLock()
+	state := c.state
+	timeout := c.healthTimeout
+	c.mu.RUnlock()
+	if timeout <= 0 {
+		timeout = DefaultHealthCheckTimeout
+	}
+
+	report := HealthReport{Status: HealthUp, State: state.String()}
+	if state != StateInitialized && group != Liveness {
+		report.Status = HealthDown
+	}
+
+	checkers := c.healthCheckers(group)
+	report.Checks = make([]ComponentHealth, len(checkers))
+	var wg sync.WaitGroup
+	for i, checker := range checkers {
+		wg.Add(1)
+		go func(i int, checker healthChecker) {
+			defer wg.Done()
+			report.Checks[i] = checker.run(ctx, timeout)
+		}(i, checker)
+	}
+	wg.Wait()
+
+	for _, check := range report.Checks {
+		if check.Status == HealthDown {
+			report.Status = HealthDown
+		}
+	}
+	return report
+}
+
+// healthChecker is a component to check
+type healthChecker struct {
+	key     componentKey
+	groups  []HealthGroup
+	checker HealthChecker
+}
+
+// healthCheckers returns the initialized health checkers of c and its
+// ancestors in group, or all of them if group is empty
+func (c *CtxbootComponentContext) healthCheckers(group HealthGroup) []healthChecker {
+	var checkers []healthChecker
+	for ctx := c; ctx != nil; ctx = ctx.parent {
+		ctx.mu.RLock()
+		for _, key := range ctx.initOrder {
+			checker, ok := ctx.components[key].(HealthChecker)
+			if !ok {
+				continue
+			}
+			groups := []HealthGroup{Readiness}
+			if grouper, ok := checker.(HealthGrouper); ok {
+				groups = grouper.HealthGroups()
+			}
+			if group == "" || containsGroup(groups, group) {
+				checkers = append(checkers, healthChecker{key: key, groups: groups, checker: checker})
+			}
+		}
+		ctx.mu.RUnlock()
+	}
+	return checkers
+}
+
+// run runs the health check, giving up after timeout or once ctx is done
+func (h healthChecker) run(ctx context.Context, timeout time.Duration) ComponentHealth {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		done <- h.checker.HealthCheck(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = fmt.Errorf("health check not completed: %w", ctx.Err())
+	}
+
+	result := ComponentHealth{
+		Component: h.key.id(),
+		Groups:    h.groups,
+		Status:    HealthUp,
+		Duration:  time.Since(start),
+		Err:       err,
+	}
+	if err != nil {
+		result.Status = HealthDown
+	}
+	return result
+}
+
+// containsGroup reports whether groups contains group
+func containsGroup(groups []HealthGroup, group HealthGroup) bool {
+	for _, g := range groups {
+		if g == group {
+			return true
+		}
+	}
+	return false
+}
+
+// HealthHandler returns an http.Handler serving the health report of group,
+// or of all checks if group is empty, as JSON, with status 200 when it is up
+// and 503 when it is down, e.g. for Kubernetes probes:
+//
+//	mux.Handle("/livez", cc.HealthHandler(ctxboot.Liveness))
+//	mux.Handle("/readyz", cc.HealthHandler(ctxboot.Readiness))
+func (c *CtxbootComponentContext) HealthHandler(group HealthGroup) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		report := c.CheckHealth(r.Context(), group)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		if report.Status == HealthUp {
+			w.WriteHeader(http.StatusOK)
+		} else {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		if r.Method != http.MethodHead {
+			json.NewEncoder(w).Encode(report)
+		}
+	})
+}
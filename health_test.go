@@ -0,0 +1,177 @@
+package ctxboot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// hcCheck is a health checker failing with err, or hanging until its
+// context is done
+type hcCheck struct {
+	err    error
+	hang   bool
+	groups []HealthGroup
+}
+
+func (h *hcCheck) HealthCheck(ctx context.Context) error {
+	if h.hang {
+		<-ctx.Done()
+		return nil
+	}
+	return h.err
+}
+
+type hcDB struct{ hcCheck }
+
+type hcCache struct{ hcCheck }
+
+type hcWorker struct{ hcCheck }
+
+func (w *hcWorker) HealthGroups() []HealthGroup { return w.groups }
+
+func newHealthContext(t *testing.T, instances ...interface{}) *CtxbootComponentContext {
+	t.Helper()
+	c := NewCtxbootComponentContext()
+	for _, instance := range instances {
+		mustRegister(t, c, instance)
+	}
+	if err := c.InitializeComponents(); err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+// checkStatuses returns the status of each check of report by component
+func checkStatuses(report HealthReport) map[string]HealthStatus {
+	statuses := make(map[string]HealthStatus)
+	for _, check := range report.Checks {
+		statuses[check.Component.String()] = check.Status
+	}
+	return statuses
+}
+
+func TestCheckHealthGroups(t *testing.T) {
+	c := newHealthContext(t,
+		&hcDB{},
+		&hcCache{hcCheck{err: errors.New("cache unreachable")}},
+		&hcWorker{hcCheck{groups: []HealthGroup{Liveness, Readiness}}},
+	)
+
+	tests := []struct {
+		group  HealthGroup
+		status HealthStatus
+		checks map[string]HealthStatus
+	}{
+		{"", HealthDown, map[string]HealthStatus{"*ctxboot.hcDB": HealthUp, "*ctxboot.hcCache": HealthDown, "*ctxboot.hcWorker": HealthUp}},
+		{Readiness, HealthDown, map[string]HealthStatus{"*ctxboot.hcDB": HealthUp, "*ctxboot.hcCache": HealthDown, "*ctxboot.hcWorker": HealthUp}},
+		{Liveness, HealthUp, map[string]HealthStatus{"*ctxboot.hcWorker": HealthUp}},
+	}
+	for _, tt := range tests {
+		report := c.CheckHealth(context.Background(), tt.group)
+		if report.Status != tt.status || report.State != "initialized" || !reflect.DeepEqual(checkStatuses(report), tt.checks) {
+			t.Errorf("CheckHealth(%q) = %s %v, want %s %v", tt.group, report.Status, checkStatuses(report), tt.status, tt.checks)
+		}
+	}
+}
+
+func TestCheckHealthTimeout(t *testing.T) {
+	c := newHealthContext(t, &hcDB{hcCheck{hang: true}}, &hcCache{})
+	c.SetHealthCheckTimeout(20 * time.Millisecond)
+
+	start := time.Now()
+	report := c.CheckHealth(context.Background(), "")
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("CheckHealth() took %v, want the timeout", elapsed)
+	}
+	if report.Status != HealthDown || len(report.Checks) != 2 {
+		t.Fatalf("CheckHealth() = %+v, want down", report)
+	}
+	db, cache := report.Checks[0], report.Checks[1]
+	if db.Status != HealthDown || !errors.Is(db.Err, context.DeadlineExceeded) {
+		t.Errorf("db check = %+v, want timed out", db)
+	}
+	if cache.Status != HealthUp {
+		t.Errorf("cache check = %+v, want up", cache)
+	}
+}
+
+func TestCheckHealthNotInitialized(t *testing.T) {
+	c := NewCtxbootComponentContext()
+	mustRegister(t, c, &hcDB{})
+
+	// Only initialized components are checked, and only liveness ignores
+	// the state
+	for group, want := range map[HealthGroup]HealthStatus{"": HealthDown, Readiness: HealthDown, Liveness: HealthUp} {
+		report := c.CheckHealth(context.Background(), group)
+		if report.Status != want || report.State != "registering" || len(report.Checks) != 0 {
+			t.Errorf("CheckHealth(%q) = %+v, want %s without checks", group, report, want)
+		}
+	}
+
+	if err := c.InitializeComponents(); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if report := c.CheckHealth(context.Background(), ""); report.Status != HealthDown || report.State != "shut down" {
+		t.Errorf("CheckHealth() = %+v after Shutdown, want down", report)
+	}
+}
+
+func TestHealthHandler(t *testing.T) {
+	c := newHealthContext(t, &hcDB{}, &hcCache{hcCheck{err: errors.New("cache unreachable")}})
+
+	tests := []struct {
+		method string
+		group  HealthGroup
+		code   int
+		body   bool
+	}{
+		{http.MethodGet, Liveness, http.StatusOK, true},
+		{http.MethodGet, Readiness, http.StatusServiceUnavailable, true},
+		{http.MethodHead, Liveness, http.StatusOK, false},
+		{http.MethodHead, Readiness, http.StatusServiceUnavailable, false},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		c.HealthHandler(tt.group).ServeHTTP(w, httptest.NewRequest(tt.method, "/", nil))
+		if w.Code != tt.code || w.Header().Get("Content-Type") != "application/json" || w.Header().Get("Cache-Control") != "no-store" {
+			t.Errorf("%s %s = %d %v, want %d", tt.method, tt.group, w.Code, w.Header(), tt.code)
+		}
+		if got := w.Body.Len() > 0; got != tt.body {
+			t.Errorf("%s %s body = %q, want a body: %v", tt.method, tt.group, w.Body.String(), tt.body)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	c.HealthHandler(Readiness).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	var report struct {
+		Status string `json:"status"`
+		Checks []struct {
+			Component string   `json:"component"`
+			Groups    []string `json:"groups"`
+			Status    string   `json:"status"`
+			Duration  string   `json:"duration"`
+			Error     string   `json:"error"`
+		} `json:"checks"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &report); err != nil {
+		t.Fatal(err)
+	}
+	if report.Status != "DOWN" || len(report.Checks) != 2 {
+		t.Fatalf("report = %+v, want down with two checks", report)
+	}
+	cache := report.Checks[1]
+	if cache.Component != "*ctxboot.hcCache" || cache.Status != "DOWN" || cache.Error != "cache unreachable" ||
+		!reflect.DeepEqual(cache.Groups, []string{"readiness"}) || !strings.HasSuffix(cache.Duration, "s") {
+		t.Errorf("cache check = %+v, want the failure", cache)
+	}
+}
@@ -0,0 +1,89 @@
+package ctxboot
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+)
+
+// Lazy is an injectable field type deferring the resolution of a dependency
+// of type T until the first call to Get:
+//
+//	Reports ctxboot.Lazy[*ReportService] `ctxboot:"inject"`
+//
+// A Lazy field is not a dependency edge for InitializeComponents, so it may be
+// used to break a cycle between two components
+type Lazy[T any] struct {
+	once    sync.Once
+	resolve func() (reflect.Value, error)
+	value   T
+	err     error
+}
+
+// Get resolves the dependency on first use and returns the same result on
+// every later call
+func (l *Lazy[T]) Get() (T, error) {
+	l.once.Do(func() {
+		l.value, l.err = resolveDeferred[T](l.resolve)
+	})
+	return l.value, l.err
+}
+
+// bind implements deferred
+func (l *Lazy[T]) bind(resolve func() (reflect.Value, error)) {
+	l.resolve = resolve
+}
+
+// elemType implements deferred
+func (l *Lazy[T]) elemType() reflect.Type {
+	return reflect.TypeOf((*T)(nil)).Elem()
+}
+
+// Provider is an injectable field type resolving a dependency of type T anew
+// on every call to Get, which yields a new instance each time for prototype
+// components. Like Lazy, it is not a dependency edge for InitializeComponents
+type Provider[T any] struct {
+	resolve func() (reflect.Value, error)
+}
+
+// Get resolves the dependency
+func (p *Provider[T]) Get() (T, error) {
+	return resolveDeferred[T](p.resolve)
+}
+
+// bind implements deferred
+func (p *Provider[T]) bind(resolve func() (reflect.Value, error)) {
+	p.resolve = resolve
+}
+
+// elemType implements deferred
+func (p *Provider[T]) elemType() reflect.Type {
+	return reflect.TypeOf((*T)(nil)).Elem()
+}
+
+// deferred is implemented by pointers to Lazy and Provider fields, which are
+// bound to a resolver instead of being set to a component
+type deferred interface {
+	bind(resolve func() (reflect.Value, error))
+	elemType() reflect.Type
+}
+
+var deferredType = reflect.TypeOf((*deferred)(nil)).Elem()
+
+// isDeferred reports whether a field of type fieldType is a Lazy or Provider
+func isDeferred(fieldType reflect.Type) bool {
+	return reflect.PtrTo(fieldType).Implements(deferredType)
+}
+
+// resolveDeferred calls the resolver bound to a Lazy or Provider
+func resolveDeferred[T any](resolve func() (reflect.Value, error)) (T, error) {
+	var zero T
+	if resolve == nil {
+		return zero, errors.New("deferred dependency was not injected")
+	}
+	value, err := resolve()
+	if err != nil {
+		return zero, err
+	}
+	return value.Interface().(T), nil
+}
@@ -0,0 +1,102 @@
+package ctxboot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Initializer is implemented by components that need to run setup logic
+// (connecting to a database, warming a cache, ...) once their dependencies
+// have been injected and initialized
+type Initializer interface {
+	Init() error
+}
+
+// Closer is implemented by components that release resources on shutdown
+type Closer interface {
+	Close() error
+}
+
+// Stopper is implemented by components whose shutdown honors a context.
+// When a component implements both Stopper and Closer only Stop is called
+type Stopper interface {
+	Stop(ctx context.Context) error
+}
+
+// runInitHook calls the Init hook of a component if it implements Initializer
+func runInitHook(instance interface{}) error {
+	if initializer, ok := instance.(Initializer); ok {
+		return initializer.Init()
+	}
+	return nil
+}
+
+// Shutdown stops every initialized component implementing Stopper or Closer,
+// in reverse of the order InitializeComponents used. It first publishes
+// ContextShuttingDown and waits for the events published with PublishAsync
+// to be delivered, then unsubscribes the listeners. All failures are
+// collected and returned together; once ctx is done the remaining components
+// are reported as not stopped
+func (c *CtxbootComponentContext) Shutdown(ctx context.Context) error {
+	var errs []error
+	if err := c.events.publish(ctx, ContextShuttingDown{}, false); err != nil {
+		errs = append(errs, fmt.Errorf("failed to publish ContextShuttingDown: %w", err))
+	}
+	if err := c.events.wait(ctx); err != nil {
+		errs = append(errs, err)
+	}
+	c.events.unsubscribeAll()
+
+	c.mu.Lock()
+	order := c.initOrder
+	c.initOrder = nil
+	c.state = StateShutDown
+	instances := make([]interface{}, len(order))
+	for i, typ := range order {
+		instances[i] = c.components[typ]
+	}
+	c.mu.Unlock()
+
+	for i := len(order) - 1; i >= 0; i-- {
+		if err := ctx.Err(); err != nil {
+			errs = append(errs, fmt.Errorf("component %v not stopped: %w", order[i], err))
+			continue
+		}
+		start := time.Now()
+		err := runShutdownHook(ctx, instances[i])
+		duration := time.Since(start)
+		c.notify(func(o Observer) { o.OnShutdown(order[i].id(), duration, err) })
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to stop component %v: %w", order[i], err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// runShutdownHook calls Stop or Close on a component, giving up when ctx is
+// done before the hook returns
+func runShutdownHook(ctx context.Context, instance interface{}) error {
+	var hook func() error
+	switch v := instance.(type) {
+	case Stopper:
+		hook = func() error { return v.Stop(ctx) }
+	case Closer:
+		hook = v.Close
+	default:
+		return nil
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- hook()
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,226 @@
+package ctxboot
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"reflect"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// ComponentID identifies a registered component in Observer events
+type ComponentID struct {
+	Type reflect.Type
+	Name string // qualifier, empty for unnamed components
+}
+
+// String returns the type of the component followed by its name, if any
+func (id ComponentID) String() string {
+	return componentKey{typ: id.Type, name: id.Name}.String()
+}
+
+// id returns the ComponentID of key
+func (k componentKey) id() ComponentID {
+	return ComponentID{Type: k.typ, Name: k.name}
+}
+
+// Observer receives the events of a context, see AddObserver. With
+// SetInitWorkers its methods may be called concurrently
+type Observer interface {
+	// OnRegister is called when a component, provider or prototype is
+	// registered
+	OnRegister(id ComponentID)
+	// OnInjectField is called when an inject or value field of a component
+	// is set, err reporting why it could not be
+	OnInjectField(id ComponentID, field string, err error)
+	// OnInitStart is called before a component is initialized: created by its
+	// provider or injected, and its Init hook run. Prototypes are initialized
+	// on every creation
+	OnInitStart(id ComponentID)
+	// OnInitDone is called once a component is initialized or failed to be
+	OnInitDone(id ComponentID, duration time.Duration, err error)
+	// OnShutdown is called once Shutdown stopped a component or failed to
+	OnShutdown(id ComponentID, duration time.Duration, err error)
+}
+
+// NopObserver implements Observer doing nothing, to be embedded by
+// observers interested in some of the events only
+type NopObserver struct{}
+
+// OnRegister implements Observer
+func (NopObserver) OnRegister(ComponentID) {}
+
+// OnInjectField implements Observer
+func (NopObserver) OnInjectField(ComponentID, string, error) {}
+
+// OnInitStart implements Observer
+func (NopObserver) OnInitStart(ComponentID) {}
+
+// OnInitDone implements Observer
+func (NopObserver) OnInitDone(ComponentID, time.Duration, error) {}
+
+// OnShutdown implements Observer
+func (NopObserver) OnShutdown(ComponentID, time.Duration, error) {}
+
+// AddObserver attaches an observer to c. Observers of a context also
+// receive the events of its child contexts
+func (c *CtxbootComponentContext) AddObserver(o Observer) {
+	c.mu.Lock()
+	c.observers = append(c.observers, o)
+	c.mu.Unlock()
+}
+
+// notify calls event with the observers of c and its ancestors
+func (c *CtxbootComponentContext) notify(event func(o Observer)) {
+	for ctx := c; ctx != nil; ctx = ctx.parent {
+		ctx.mu.RLock()
+		observers := ctx.observers
+		ctx.mu.RUnlock()
+		for _, o := range observers {
+			event(o)
+		}
+	}
+}
+
+// initStarted notifies the start of the initialization of key and returns
+// its start time
+func (c *CtxbootComponentContext) initStarted(key componentKey) time.Time {
+	c.notify(func(o Observer) { o.OnInitStart(key.id()) })
+	return time.Now()
+}
+
+// initDone notifies the end of the initialization of key and, for
+// singletons, records it
+func (c *CtxbootComponentContext) initDone(key componentKey, start time.Time, err error) {
+	duration := time.Since(start)
+	c.mu.Lock()
+	if _, isPrototype := c.prototypes[key]; !isPrototype {
+		if c.inits == nil {
+			c.inits = make(map[componentKey]InitTiming)
+		}
+		c.inits[key] = InitTiming{Component: key.id(), Duration: duration, Err: err}
+	}
+	c.mu.Unlock()
+	c.notify(func(o Observer) { o.OnInitDone(key.id(), duration, err) })
+}
+
+// slogObserver logs the events of a context
+type slogObserver struct {
+	logger *slog.Logger
+}
+
+// NewSlogObserver returns an observer logging registrations and injections
+// at debug level, initializations and shutdowns at info level, and failures
+// at error level
+func NewSlogObserver(logger *slog.Logger) Observer {
+	return slogObserver{logger: logger}
+}
+
+// OnRegister implements Observer
+func (o slogObserver) OnRegister(id ComponentID) {
+	o.logger.Debug("component registered", "component", id.String())
+}
+
+// OnInjectField implements Observer
+func (o slogObserver) OnInjectField(id ComponentID, field string, err error) {
+	if err != nil {
+		o.logger.Error("field injection failed", "component", id.String(), "field", field, "error", err)
+		return
+	}
+	o.logger.Debug("field injected", "component", id.String(), "field", field)
+}
+
+// OnInitStart implements Observer
+func (o slogObserver) OnInitStart(id ComponentID) {
+	o.logger.Debug("component initializing", "component", id.String())
+}
+
+// OnInitDone implements Observer
+func (o slogObserver) OnInitDone(id ComponentID, duration time.Duration, err error) {
+	o.log(err, "component initialized", "component initialization failed", id, duration)
+}
+
+// OnShutdown implements Observer
+func (o slogObserver) OnShutdown(id ComponentID, duration time.Duration, err error) {
+	o.log(err, "component stopped", "component shutdown failed", id, duration)
+}
+
+// log logs the end of an initialization or shutdown
+func (o slogObserver) log(err error, msg, failMsg string, id ComponentID, duration time.Duration) {
+	if err != nil {
+		o.logger.LogAttrs(context.Background(), slog.LevelError, failMsg,
+			slog.String("component", id.String()), slog.Duration("duration", duration), slog.Any("error", err))
+		return
+	}
+	o.logger.LogAttrs(context.Background(), slog.LevelInfo, msg,
+		slog.String("component", id.String()), slog.Duration("duration", duration))
+}
+
+// InitTiming is the initialization time of a component, see TimingObserver
+type InitTiming struct {
+	Component ComponentID
+	Duration  time.Duration // including the time spent in Init
+	Err       error
+}
+
+// TimingObserver records how long each component took to initialize:
+//
+//	timing := &ctxboot.TimingObserver{}
+//	cc.AddObserver(timing)
+//	err := cc.InitializeComponents()
+//	timing.WriteReport(os.Stderr)
+type TimingObserver struct {
+	NopObserver
+	mu      sync.Mutex
+	timings []InitTiming
+}
+
+// OnInitDone implements Observer
+func (t *TimingObserver) OnInitDone(id ComponentID, duration time.Duration, err error) {
+	t.mu.Lock()
+	t.timings = append(t.timings, InitTiming{Component: id, Duration: duration, Err: err})
+	t.mu.Unlock()
+}
+
+// Timings returns the recorded initializations, slowest first
+func (t *TimingObserver) Timings() []InitTiming {
+	t.mu.Lock()
+	timings := append([]InitTiming(nil), t.timings...)
+	t.mu.Unlock()
+
+	sort.SliceStable(timings, func(i, j int) bool {
+		return timings[i].Duration > timings[j].Duration
+	})
+	return timings
+}
+
+// WriteReport writes a startup report listing the initializations, slowest
+// first, with their share of the total initialization time
+func (t *TimingObserver) WriteReport(w io.Writer) error {
+	timings := t.Timings()
+
+	var total time.Duration
+	for _, timing := range timings {
+		total += timing.Duration
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "COMPONENT\tDURATION\tSHARE\t")
+	for _, timing := range timings {
+		share := 0.0
+		if total > 0 {
+			share = 100 * float64(timing.Duration) / float64(total)
+		}
+		status := ""
+		if timing.Err != nil {
+			status = "failed"
+		}
+		fmt.Fprintf(tw, "%v\t%v\t%.1f%%\t%s\n", timing.Component, timing.Duration.Round(time.Microsecond), share, status)
+	}
+	fmt.Fprintf(tw, "total (%d components)\t%v\t\t\n", len(timings), total.Round(time.Microsecond))
+	return tw.Flush()
+}
@@ -0,0 +1,256 @@
+package ctxboot
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// Snapshot is the state of the registrations of a context, see
+// CtxbootComponentContext.Snapshot
+type Snapshot struct {
+	components map[componentKey]interface{}
+	providers  map[componentKey]reflect.Value
+	prototypes map[componentKey]func() interface{}
+	primary    map[componentKey]bool
+	order      []componentKey
+	initOrder  []componentKey // components initialized when taken
+}
+
+// Snapshot records the registrations of c, and the instances of its
+// components, to be restored with Restore
+func (c *CtxbootComponentContext) Snapshot() *Snapshot {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return &Snapshot{
+		components: copyMap(c.components),
+		providers:  copyMap(c.providers),
+		prototypes: copyMap(c.prototypes),
+		primary:    copyMap(c.primary),
+		order:      append([]componentKey(nil), c.order...),
+		initOrder:  append([]componentKey(nil), c.initOrder...),
+	}
+}
+
+// Restore brings the registrations of c back to the snapshot s. Once c is
+// initialized, the components that had a replaced component injected are
+// injected again with the restored one. Restored instances that were
+// initialized when s was taken are not initialized again
+func (c *CtxbootComponentContext) Restore(s *Snapshot) error {
+	c.mu.Lock()
+	if c.state == StateInitializing {
+		c.mu.Unlock()
+		return &Error{Err: ErrContextSealed, Detail: fmt.Sprintf("context is %v", c.state)}
+	}
+	changed := c.changedSince(s)
+	initialized := c.state == StateInitialized
+	c.mu.Unlock()
+
+	if !initialized || len(changed) == 0 {
+		c.restore(s)
+		return nil
+	}
+
+	// Dependents are looked up both before and after restoring, to follow
+	// injection points resolving to an override as well as to the original
+	affected, err := c.dependentsOf(changed)
+	if err != nil {
+		return err
+	}
+	c.restore(s)
+	restored, err := c.dependentsOf(changed)
+	if err != nil {
+		return err
+	}
+	for key := range restored {
+		affected[key] = true
+	}
+
+	c.mu.RLock()
+	order := append([]componentKey(nil), c.initOrder...)
+	c.mu.RUnlock()
+
+	for _, key := range order {
+		if changed[key] {
+			if !containsKey(s.initOrder, key) {
+				if err := c.initializeComponent(key); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if !affected[key] {
+			continue
+		}
+
+		// Provided components that are not changed were created from the
+		// restored dependencies already
+		c.mu.RLock()
+		instance := c.components[key]
+		_, isProvided := c.providers[key]
+		c.mu.RUnlock()
+		if isProvided {
+			continue
+		}
+		if err := c.injectDependencies(key, instance); err != nil {
+			return fmt.Errorf("failed to re-inject component %v: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// WithOverrides replaces the components of c resolving to the types of
+// overrides with the given instances, until restore is called. The
+// overrides are registered under their type, unnamed, so that they win over
+// the components they replace, including for interfaces. Unlike
+// registrations, overrides are allowed once c is initialized, re-injecting
+// the components depending on them as with EnableUnsafeReinjection. An
+// override made then has its own dependencies injected but skips the
+// lifecycle: its Init hook does not run and Shutdown does not stop it.
+// Overrides made before InitializeComponents are initialized like any
+// component:
+//
+//	restore, err := cc.WithOverrides(map[reflect.Type]interface{}{
+//	    reflect.TypeOf((*Mailer)(nil)).Elem(): &FakeMailer{},
+//	})
+//	defer restore()
+func (c *CtxbootComponentContext) WithOverrides(overrides map[reflect.Type]interface{}) (restore func() error, err error) {
+	snapshot := c.Snapshot()
+	restore = func() error { return c.Restore(snapshot) }
+
+	for typ, instance := range overrides {
+		if err := c.override(lookupType(typ), instance); err != nil {
+			return nil, errors.Join(err, restore())
+		}
+	}
+	return restore, nil
+}
+
+// Override replaces the component resolving to T with v until restore is
+// called, see CtxbootComponentContext.WithOverrides
+func Override[T any](c Container, v T) (restore func() error, err error) {
+	return c.componentContext().WithOverrides(map[reflect.Type]interface{}{typeOf[T](): v})
+}
+
+// override registers instance under typ regardless of c being sealed, and
+// re-injects its dependents once c is initialized
+func (c *CtxbootComponentContext) override(typ reflect.Type, instance interface{}) error {
+	key := componentKey{typ: typ}
+	if instance == nil {
+		return newError(ErrNotAssignable, key, "nil override")
+	}
+	instance, err := assignableInstance(key, instance)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	state := c.state
+	if state != StateRegistering && state != StateInitialized {
+		c.mu.Unlock()
+		return newError(ErrContextSealed, key, "context is %v", state)
+	}
+	c.storeComponent(key, instance)
+	if state == StateInitialized {
+		c.initOrder = withoutKey(c.initOrder, key)
+	}
+	c.mu.Unlock()
+
+	c.notify(func(o Observer) { o.OnRegister(key.id()) })
+	if state != StateInitialized {
+		return nil
+	}
+	if typ := reflect.TypeOf(instance); typ.Kind() == reflect.Ptr && typ.Elem().Kind() == reflect.Struct {
+		if err := c.injectDependencies(key, instance); err != nil {
+			return fmt.Errorf("failed to inject override %v: %w", key, err)
+		}
+	}
+	return c.refreshDependents(key)
+}
+
+// changedSince returns the keys registered differently in c and in s, or
+// with different instances. Caller must hold c.mu
+func (c *CtxbootComponentContext) changedSince(s *Snapshot) map[componentKey]bool {
+	changed := make(map[componentKey]bool)
+	for _, key := range append(append([]componentKey(nil), c.order...), s.order...) {
+		current, inCurrent := c.components[key]
+		saved, inSaved := s.components[key]
+		_, providedNow := c.providers[key]
+		_, providedBefore := s.providers[key]
+		_, prototypeNow := c.prototypes[key]
+		_, prototypeBefore := s.prototypes[key]
+		if inCurrent != inSaved || providedNow != providedBefore || prototypeNow != prototypeBefore ||
+			(inCurrent && !sameInstance(current, saved)) {
+			changed[key] = true
+		}
+	}
+	return changed
+}
+
+// restore replaces the registrations of c with the ones of s. The
+// components initialized when s was taken keep their initialization order,
+// unless c was shut down since, followed by the ones initialized since that
+// s has
+func (c *CtxbootComponentContext) restore(s *Snapshot) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.components = copyMap(s.components)
+	c.providers = copyMap(s.providers)
+	c.prototypes = copyMap(s.prototypes)
+	c.primary = copyMap(s.primary)
+	c.order = append([]componentKey(nil), s.order...)
+
+	previous := c.initOrder
+	if c.state != StateShutDown {
+		previous = append(append([]componentKey(nil), s.initOrder...), previous...)
+	}
+	var initOrder []componentKey
+	for _, key := range previous {
+		if c.registered(key) && !containsKey(initOrder, key) {
+			initOrder = append(initOrder, key)
+		}
+	}
+	c.initOrder = initOrder
+}
+
+// dependentsOf returns the initialized components depending on one of keys
+func (c *CtxbootComponentContext) dependentsOf(keys map[componentKey]bool) (map[componentKey]bool, error) {
+	c.mu.RLock()
+	order := append([]componentKey(nil), c.initOrder...)
+	c.mu.RUnlock()
+
+	dependents := make(map[componentKey]bool)
+	for _, key := range order {
+		deps, err := c.componentDependencies(key)
+		if err != nil {
+			return nil, err
+		}
+		if containsAny(deps, keys) {
+			dependents[key] = true
+		}
+	}
+	return dependents, nil
+}
+
+// sameInstance reports whether a and b are the same component instance
+func sameInstance(a, b interface{}) bool {
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	if va.Type() != vb.Type() {
+		return false
+	}
+	switch va.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice, reflect.Func, reflect.Chan, reflect.UnsafePointer:
+		return va.Pointer() == vb.Pointer()
+	}
+	return va.Type().Comparable() && a == b
+}
+
+// copyMap returns a shallow copy of m
+func copyMap[K comparable, V any](m map[K]V) map[K]V {
+	copied := make(map[K]V, len(m))
+	for k, v := range m {
+		copied[k] = v
+	}
+	return copied
+}
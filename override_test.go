@@ -0,0 +1,205 @@
+package ctxboot
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+type ovMailer interface {
+	Send(to string)
+}
+
+// ovLifecycle counts the Init and Close hooks of a component
+type ovLifecycle struct {
+	inits, closes int
+}
+
+func (l *ovLifecycle) Init() error {
+	l.inits++
+	return nil
+}
+
+func (l *ovLifecycle) Close() error {
+	l.closes++
+	return nil
+}
+
+type ovSMTP struct {
+	ovLifecycle
+}
+
+func (*ovSMTP) Send(to string) {}
+
+type ovFake struct {
+	ovLifecycle
+	Templates *ovTemplates `ctxboot:"inject"`
+	sent      []string
+}
+
+func (f *ovFake) Send(to string) { f.sent = append(f.sent, to) }
+
+type ovTemplates struct {
+	dir string
+}
+
+type ovSignup struct {
+	Mailer ovMailer `ctxboot:"inject"`
+}
+
+func newOverrideContext(t *testing.T) (*CtxbootComponentContext, *ovSMTP, *ovSignup) {
+	t.Helper()
+	c := NewCtxbootComponentContext()
+	smtp, signup := &ovSMTP{}, &ovSignup{}
+	mustRegister(t, c, smtp)
+	mustRegister(t, c, &ovTemplates{})
+	mustRegister(t, c, signup)
+	return c, smtp, signup
+}
+
+func TestOverrideInitialized(t *testing.T) {
+	c, smtp, signup := newOverrideContext(t)
+	if err := c.InitializeComponents(); err != nil {
+		t.Fatal(err)
+	}
+
+	fake := &ovFake{}
+	restore, err := Override[ovMailer](c, fake)
+	if err != nil {
+		t.Fatalf("Override() = %v", err)
+	}
+	if signup.Mailer != fake || MustGet[ovMailer](c) != fake {
+		t.Errorf("Mailer = %v, want the fake", signup.Mailer)
+	}
+	if fake.Templates == nil {
+		t.Error("the dependencies of the fake were not injected")
+	}
+
+	if err := restore(); err != nil {
+		t.Fatalf("restore() = %v", err)
+	}
+	if signup.Mailer != smtp || MustGet[ovMailer](c) != smtp {
+		t.Errorf("Mailer = %v after restore, want the original", signup.Mailer)
+	}
+
+	// The fake skipped the lifecycle, the original was not initialized again
+	if err := c.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if fake.inits != 0 || fake.closes != 0 {
+		t.Errorf("fake Init and Close ran %d and %d times, want none", fake.inits, fake.closes)
+	}
+	if smtp.inits != 1 || smtp.closes != 1 {
+		t.Errorf("original Init and Close ran %d and %d times, want once", smtp.inits, smtp.closes)
+	}
+}
+
+func TestOverrideNotStopped(t *testing.T) {
+	c, _, _ := newOverrideContext(t)
+	if err := c.InitializeComponents(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Overriding a component by its own type drops it from the shutdown
+	// order too
+	replacement := &ovSMTP{}
+	if _, err := Override[*ovSMTP](c, replacement); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if replacement.inits != 0 || replacement.closes != 0 {
+		t.Errorf("override Init and Close ran %d and %d times, want none", replacement.inits, replacement.closes)
+	}
+}
+
+func TestRestoreSnapshotBeforeInit(t *testing.T) {
+	c, smtp, signup := newOverrideContext(t)
+	snapshot := c.Snapshot()
+
+	// Overrides made before initialization are initialized
+	fake := &ovFake{}
+	if _, err := Override[ovMailer](c, fake); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.InitializeComponents(); err != nil {
+		t.Fatal(err)
+	}
+	if signup.Mailer != fake || fake.inits != 1 || smtp.inits != 1 {
+		t.Fatalf("Mailer = %v with %d and %d inits, want the fake, both initialized", signup.Mailer, fake.inits, smtp.inits)
+	}
+
+	if err := c.Restore(snapshot); err != nil {
+		t.Fatalf("Restore() = %v", err)
+	}
+	if signup.Mailer != smtp || smtp.inits != 1 {
+		t.Errorf("Mailer = %v with %d inits, want the original initialized once", signup.Mailer, smtp.inits)
+	}
+	if _, err := Get[*ovFake](c); !isMissing(err) {
+		t.Errorf("Get(*ovFake) error = %v, want the override gone", err)
+	}
+}
+
+func TestRestoreSnapshotAfterInit(t *testing.T) {
+	c, smtp, signup := newOverrideContext(t)
+	if err := c.InitializeComponents(); err != nil {
+		t.Fatal(err)
+	}
+	snapshot := c.Snapshot()
+
+	first, second := &ovFake{}, &ovFake{}
+	if _, err := Override[ovMailer](c, first); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Override[ovMailer](c, second); err != nil {
+		t.Fatal(err)
+	}
+	if signup.Mailer != second {
+		t.Fatalf("Mailer = %v, want the last override", signup.Mailer)
+	}
+
+	if err := c.Restore(snapshot); err != nil {
+		t.Fatalf("Restore() = %v", err)
+	}
+	if signup.Mailer != smtp || smtp.inits != 1 {
+		t.Errorf("Mailer = %v with %d inits, want the original initialized once", signup.Mailer, smtp.inits)
+	}
+	if !reflect.DeepEqual(c.initOrder, snapshot.initOrder) {
+		t.Errorf("initOrder = %v, want %v", c.initOrder, snapshot.initOrder)
+	}
+
+	// Restoring an unchanged context is a no-op
+	if err := c.Restore(snapshot); err != nil || signup.Mailer != smtp {
+		t.Errorf("Restore() = %v, Mailer = %v, want the original", err, signup.Mailer)
+	}
+}
+
+func TestChangedSinceAndDependentsOf(t *testing.T) {
+	c, _, _ := newOverrideContext(t)
+	if err := c.InitializeComponents(); err != nil {
+		t.Fatal(err)
+	}
+	snapshot := c.Snapshot()
+	if _, err := Override[*ovSMTP](c, &ovSMTP{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Override[*ovTemplates](c, &ovTemplates{}); err != nil {
+		t.Fatal(err)
+	}
+
+	smtp := componentKey{typ: reflect.TypeOf(&ovSMTP{})}
+	templates := componentKey{typ: reflect.TypeOf(&ovTemplates{})}
+	signup := componentKey{typ: reflect.TypeOf(&ovSignup{})}
+	c.mu.RLock()
+	changed := c.changedSince(snapshot)
+	c.mu.RUnlock()
+	if want := map[componentKey]bool{smtp: true, templates: true}; !reflect.DeepEqual(changed, want) {
+		t.Errorf("changedSince() = %v, want %v", changed, want)
+	}
+
+	dependents, err := c.dependentsOf(map[componentKey]bool{smtp: true})
+	if want := map[componentKey]bool{signup: true}; err != nil || !reflect.DeepEqual(dependents, want) {
+		t.Errorf("dependentsOf() = %v, %v, want %v", dependents, err, want)
+	}
+}
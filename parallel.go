@@ -0,0 +1,110 @@
+package ctxboot
+
+// SetInitWorkers sets how many components InitializeComponents may initialize
+// at once. With more than one worker, independent branches of the dependency
+// graph are initialized concurrently, a component still starting only once
+// all of its dependencies are done. On the first failure no further component
+// is started, those already running are waited for, and the failure is
+// returned. The default, 1, initializes components one after the other
+func (c *CtxbootComponentContext) SetInitWorkers(n int) {
+	c.mu.Lock()
+	c.initWorkers = n
+	c.mu.Unlock()
+}
+
+// initializeConcurrently initializes the components registered under order
+// with up to workers goroutines, appending them to initOrder as they complete.
+// Components resumed from a failed attempt are started like startComponent
+// does
+func (c *CtxbootComponentContext) initializeConcurrently(order []componentKey, workers int, resumed map[componentKey]bool, initOrder *[]componentKey) error {
+	// Count the dependencies each component waits for
+	waiting := make(map[componentKey]int, len(order))
+	dependents := make(map[componentKey][]componentKey)
+	depsOf := make(map[componentKey][]componentKey, len(order))
+	for _, key := range order {
+		deps, err := c.componentDependencies(key)
+		if err != nil {
+			return err
+		}
+		depsOf[key] = deps
+		seen := make(map[componentKey]bool)
+		for _, dep := range deps {
+			if !seen[dep] {
+				seen[dep] = true
+				waiting[key]++
+				dependents[dep] = append(dependents[dep], key)
+			}
+		}
+	}
+
+	var ready []componentKey
+	for _, key := range order {
+		if waiting[key] == 0 {
+			ready = append(ready, key)
+		}
+	}
+
+	type result struct {
+		key     componentKey
+		started bool
+		err     error
+	}
+	results := make(chan result)
+	running, done := 0, make(map[componentKey]bool, len(order))
+	fresh := make(map[componentKey]bool) // initialized with a new instance
+	var failure error
+
+	for {
+		// Start ready components, unless a component failed
+		for failure == nil && running < workers && len(ready) > 0 {
+			key := ready[0]
+			ready = ready[1:]
+			running++
+			stale := containsAny(depsOf[key], fresh)
+			go func() {
+				started, err := c.startComponent(key, resumed[key], stale)
+				results <- result{key: key, started: started, err: err}
+			}()
+		}
+		if running == 0 {
+			break
+		}
+
+		r := <-results
+		running--
+		if r.err != nil {
+			if failure == nil {
+				failure = r.err
+			}
+			continue
+		}
+
+		done[r.key] = true
+		if r.started {
+			fresh[r.key] = true
+			if !resumed[r.key] {
+				*initOrder = append(*initOrder, r.key)
+			}
+		}
+		for _, dependent := range dependents[r.key] {
+			if waiting[dependent]--; waiting[dependent] == 0 {
+				ready = append(ready, dependent)
+			}
+		}
+	}
+
+	if failure != nil {
+		return failure
+	}
+	if len(done) < len(order) {
+		// Report the cycles among the uninitialized components
+		var uninitialized []componentKey
+		for _, key := range order {
+			if !done[key] {
+				uninitialized = append(uninitialized, key)
+			}
+		}
+		return c.cycleError(uninitialized)
+	}
+	return nil
+}
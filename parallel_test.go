@@ -0,0 +1,205 @@
+package ctxboot
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recorder records the start and end of Init hooks, in order
+type recorder struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (r *recorder) record(event string) {
+	r.mu.Lock()
+	r.events = append(r.events, event)
+	r.mu.Unlock()
+}
+
+// index returns the position of event, or -1
+func (r *recorder) index(event string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, e := range r.events {
+		if e == event {
+			return i
+		}
+	}
+	return -1
+}
+
+// probe records its Init hook, which takes delay and fails if fail is set
+type probe struct {
+	rec   *recorder
+	name  string
+	delay time.Duration
+	fail  bool
+}
+
+func (p *probe) Init() error {
+	p.rec.record("start " + p.name)
+	time.Sleep(p.delay)
+	p.rec.record("end " + p.name)
+	if p.fail {
+		return errors.New(p.name + " failed")
+	}
+	return nil
+}
+
+type parTop struct {
+	probe
+	Left  *parLeft  `ctxboot:"inject"`
+	Right *parRight `ctxboot:"inject"`
+}
+
+type parLeft struct {
+	probe
+	Bottom *parBottom `ctxboot:"inject"`
+}
+
+type parRight struct {
+	probe
+	Bottom *parBottom `ctxboot:"inject"`
+}
+
+type parBottom struct {
+	probe
+}
+
+type parLeaf struct {
+	probe
+}
+
+type parAfterFailure struct {
+	probe
+	Failing *parLeaf `ctxboot:"inject,name=failing"`
+}
+
+func TestInitializeConcurrentlyOrder(t *testing.T) {
+	rec := &recorder{}
+	newProbe := func(name string) probe {
+		return probe{rec: rec, name: name, delay: 5 * time.Millisecond}
+	}
+
+	c := NewCtxbootComponentContext()
+	c.SetInitWorkers(4)
+	mustRegister(t, c, &parTop{probe: newProbe("top")})
+	mustRegister(t, c, &parLeft{probe: newProbe("left")})
+	mustRegister(t, c, &parRight{probe: newProbe("right")})
+	mustRegister(t, c, &parBottom{probe: newProbe("bottom")})
+	if err := c.InitializeComponents(); err != nil {
+		t.Fatal(err)
+	}
+
+	edges := [][2]string{{"bottom", "left"}, {"bottom", "right"}, {"left", "top"}, {"right", "top"}}
+	for _, edge := range edges {
+		end, start := rec.index("end "+edge[0]), rec.index("start "+edge[1])
+		if end < 0 || start < 0 || end > start {
+			t.Errorf("%s started before %s ended: %v", edge[1], edge[0], rec.events)
+		}
+	}
+
+	// Independent branches overlap
+	if rec.index("start right") > rec.index("end left") && rec.index("start left") > rec.index("end right") {
+		t.Errorf("left and right were not initialized concurrently: %v", rec.events)
+	}
+	if got := len(c.initOrder); got != 4 || c.initOrder[0].typ != reflect.TypeOf(&parBottom{}) || c.initOrder[3].typ != reflect.TypeOf(&parTop{}) {
+		t.Errorf("initOrder = %v, want bottom first and top last", c.initOrder)
+	}
+}
+
+func TestInitializeConcurrentlyFailure(t *testing.T) {
+	rec := &recorder{}
+	c := NewCtxbootComponentContext()
+	c.SetInitWorkers(2)
+	failing := &parLeaf{probe: probe{rec: rec, name: "failing", fail: true}}
+	slow := &parLeaf{probe: probe{rec: rec, name: "slow", delay: 20 * time.Millisecond}}
+	if err := c.SetNamedComponent("failing", reflect.TypeOf(failing), failing); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.SetNamedComponent("slow", reflect.TypeOf(slow), slow); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a", "b", "c", "d"} {
+		leaf := &parLeaf{probe: probe{rec: rec, name: name, delay: 20 * time.Millisecond}}
+		if err := c.SetNamedComponent(name, reflect.TypeOf(leaf), leaf); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mustRegister(t, c, &parAfterFailure{probe: probe{rec: rec, name: "after"}})
+
+	if err := c.InitializeComponents(); err == nil || !strings.Contains(err.Error(), "failing failed") {
+		t.Fatalf("InitializeComponents() = %v, want the failure", err)
+	}
+
+	// Only the two components started with the failing one ran, and the
+	// running one was waited for
+	rec.mu.Lock()
+	events := append([]string(nil), rec.events...)
+	rec.mu.Unlock()
+	want := []string{"start failing", "end failing", "start slow", "end slow"}
+	if len(events) != len(want) {
+		t.Fatalf("events = %v, want %v in any interleaving", events, want)
+	}
+	for _, event := range want {
+		if rec.index(event) < 0 {
+			t.Errorf("events = %v, missing %q", events, event)
+		}
+	}
+	if c.State() != StateRegistering {
+		t.Errorf("State() = %v, want registering", c.State())
+	}
+}
+
+type parCycleA struct {
+	B *parCycleB `ctxboot:"inject"`
+}
+
+type parCycleB struct {
+	C *parCycleC `ctxboot:"inject"`
+}
+
+type parCycleC struct {
+	A *parCycleA `ctxboot:"inject"`
+}
+
+type parCycleFree struct{}
+
+type parCycleUser struct {
+	A *parCycleA `ctxboot:"inject"`
+}
+
+func TestInitializeConcurrentlyCycle(t *testing.T) {
+	errs := make(map[int]string)
+	for _, workers := range []int{1, 4} {
+		c := NewCtxbootComponentContext()
+		c.SetInitWorkers(workers)
+		mustRegister(t, c, &parCycleUser{})
+		mustRegister(t, c, &parCycleFree{})
+		mustRegister(t, c, &parCycleA{})
+		mustRegister(t, c, &parCycleB{})
+		mustRegister(t, c, &parCycleC{})
+
+		err := c.InitializeComponents()
+		if !errors.Is(err, ErrCircularDependency) {
+			t.Fatalf("workers=%d: InitializeComponents() = %v, want ErrCircularDependency", workers, err)
+		}
+		var cerr *Error
+		if !errors.As(err, &cerr) {
+			t.Fatalf("workers=%d: %v is not an *Error", workers, err)
+		}
+		want := []string{"ctxboot.parCycleA.B", "ctxboot.parCycleB.C", "ctxboot.parCycleC.A", "*ctxboot.parCycleA"}
+		if !reflect.DeepEqual(cerr.Path, want) {
+			t.Errorf("workers=%d: Path = %v, want %v", workers, cerr.Path, want)
+		}
+		errs[workers] = err.Error()
+	}
+	if errs[1] != errs[4] {
+		t.Errorf("serial and concurrent errors differ:\n%s\n%s", errs[1], errs[4])
+	}
+}
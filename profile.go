@@ -0,0 +1,127 @@
+package ctxboot
+
+import (
+	"os"
+	"reflect"
+	"strings"
+)
+
+// ProfilesProperty is the property listing the active profiles, comma
+// separated, when SetProfiles was not called, e.g. --ctxboot.profiles.active=dev
+// or CTXBOOT_PROFILES_ACTIVE=dev
+const ProfilesProperty = "ctxboot.profiles.active"
+
+// DefaultProfile is active when no other profile is
+const DefaultProfile = "default"
+
+// SetProfiles sets the active profiles, overriding ProfilesProperty. Child
+// contexts without profiles use those of their parent
+func (c *CtxbootComponentContext) SetProfiles(profiles ...string) {
+	c.mu.Lock()
+	c.profiles = append([]string{}, profiles...)
+	c.mu.Unlock()
+}
+
+// ActiveProfiles returns the profiles set with SetProfiles on c or its
+// closest ancestor, or else those listed by ProfilesProperty, or else
+// DefaultProfile
+func (c *CtxbootComponentContext) ActiveProfiles() []string {
+	for ctx := c; ctx != nil; ctx = ctx.parent {
+		ctx.mu.RLock()
+		profiles := ctx.profiles
+		ctx.mu.RUnlock()
+		if profiles != nil {
+			return append([]string{}, profiles...)
+		}
+	}
+
+	var profiles []string
+	if value, ok := c.properties().Property(ProfilesProperty); ok {
+		for _, profile := range strings.Split(value, ",") {
+			if profile = strings.TrimSpace(profile); profile != "" {
+				profiles = append(profiles, profile)
+			}
+		}
+	}
+	if len(profiles) == 0 {
+		return []string{DefaultProfile}
+	}
+	return profiles
+}
+
+// Condition describes when a component is registered, every non-empty field
+// having to be satisfied. The generated registerScanedComponenets evaluates
+// the conditions of //ctxboot:component and //ctxboot:provider annotations
+type Condition struct {
+	// Profile lists profiles, comma separated, one of which must be active.
+	// A profile prefixed with ! matches when it is not active, e.g. !prod
+	Profile string
+	// OnEnv names an environment variable that must be set and not empty,
+	// or NAME=value for one that must have the given value
+	OnEnv string
+	// OnProperty names a property that must be set and not false, or
+	// key=value for one that must have the given value
+	OnProperty string
+	// OnMissing is a type no component must be registered for yet
+	OnMissing reflect.Type
+}
+
+// Matches reports whether cond is satisfied by c
+func (c *CtxbootComponentContext) Matches(cond Condition) bool {
+	if cond.Profile != "" && !c.profileMatches(cond.Profile) {
+		return false
+	}
+	if cond.OnEnv != "" {
+		name, want, hasValue := strings.Cut(cond.OnEnv, "=")
+		value := os.Getenv(name)
+		if (hasValue && value != want) || (!hasValue && value == "") {
+			return false
+		}
+	}
+	if cond.OnProperty != "" {
+		key, want, hasValue := strings.Cut(cond.OnProperty, "=")
+		value, ok := c.properties().Property(key)
+		if (hasValue && value != want) || (!hasValue && (!ok || strings.EqualFold(value, "false"))) {
+			return false
+		}
+	}
+	if cond.OnMissing != nil && c.provides(cond.OnMissing) {
+		return false
+	}
+	return true
+}
+
+// profileMatches reports whether one of the comma separated profiles of expr
+// matches the active profiles
+func (c *CtxbootComponentContext) profileMatches(expr string) bool {
+	active := make(map[string]bool)
+	for _, profile := range c.ActiveProfiles() {
+		active[profile] = true
+	}
+
+	for _, profile := range strings.Split(expr, ",") {
+		profile = strings.TrimSpace(profile)
+		if name, negated := strings.CutPrefix(profile, "!"); negated {
+			if !active[name] {
+				return true
+			}
+		} else if active[profile] {
+			return true
+		}
+	}
+	return false
+}
+
+// provides reports whether c or one of its ancestors has a component
+// satisfying typ
+func (c *CtxbootComponentContext) provides(typ reflect.Type) bool {
+	for ctx := c; ctx != nil; ctx = ctx.parent {
+		ctx.mu.RLock()
+		found := len(ctx.candidates(typ, "")) > 0
+		ctx.mu.RUnlock()
+		if found {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,377 @@
+package ctxboot
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// PropertySource supplies configuration properties by dotted key, such as
+// server.port
+type PropertySource interface {
+	Property(key string) (string, bool)
+}
+
+// Properties resolves configuration properties from layered sources, later
+// sources overriding earlier ones:
+//
+//	props := ctxboot.NewProperties(
+//		ctxboot.MapSource(defaults),
+//		ctxboot.MustSource(ctxboot.YAMLFile("config.yaml")),
+//		ctxboot.EnvSource(),
+//		ctxboot.ArgsSource(os.Args[1:]),
+//	)
+type Properties struct {
+	sources []PropertySource
+}
+
+// NewProperties creates properties layering the given sources
+func NewProperties(sources ...PropertySource) *Properties {
+	return &Properties{sources: sources}
+}
+
+// Add adds a source overriding all the sources added before it
+func (p *Properties) Add(source PropertySource) {
+	p.sources = append(p.sources, source)
+}
+
+// Property returns the value of key from the last source defining it.
+// Properties is itself a PropertySource
+func (p *Properties) Property(key string) (string, bool) {
+	for i := len(p.sources) - 1; i >= 0; i-- {
+		if value, ok := p.sources[i].Property(key); ok {
+			return value, true
+		}
+	}
+	return "", false
+}
+
+// SetProperties sets the properties used by InitializeComponents to set value
+// fields and bind config components. Child contexts without properties use
+// those of their parent
+func (c *CtxbootComponentContext) SetProperties(properties *Properties) {
+	c.mu.Lock()
+	c.props = properties
+	c.mu.Unlock()
+}
+
+// properties returns the properties of c or of its closest ancestor having
+// some, or empty properties
+func (c *CtxbootComponentContext) properties() *Properties {
+	for ctx := c; ctx != nil; ctx = ctx.parent {
+		ctx.mu.RLock()
+		props := ctx.props
+		ctx.mu.RUnlock()
+		if props != nil {
+			return props
+		}
+	}
+	return NewProperties()
+}
+
+// mapSource is a PropertySource backed by a map of canonical keys
+type mapSource map[string]string
+
+// MapSource returns a source serving the given values, e.g. defaults.
+// Keys match regardless of case, dashes and underscores, so server.max-conns
+// also matches server.maxConns
+func MapSource(values map[string]string) PropertySource {
+	source := make(mapSource, len(values))
+	for key, value := range values {
+		source[canonicalKey(key)] = value
+	}
+	return source
+}
+
+// Property implements PropertySource
+func (s mapSource) Property(key string) (string, bool) {
+	value, ok := s[canonicalKey(key)]
+	return value, ok
+}
+
+// canonicalKey lowercases key and drops dashes and underscores
+func canonicalKey(key string) string {
+	return strings.NewReplacer("-", "", "_", "").Replace(strings.ToLower(key))
+}
+
+// envSource is a PropertySource looking keys up as environment variables
+type envSource func(name string) (string, bool)
+
+// EnvSource returns a source reading environment variables, the key
+// db.max-conns being read from DB_MAX_CONNS or DB_MAXCONNS
+func EnvSource() PropertySource {
+	return envSource(os.LookupEnv)
+}
+
+// Property implements PropertySource
+func (s envSource) Property(key string) (string, bool) {
+	upper := strings.ToUpper(key)
+	for _, name := range []string{
+		strings.NewReplacer(".", "_", "-", "_").Replace(upper),
+		strings.NewReplacer(".", "_", "-", "").Replace(upper),
+	} {
+		if value, ok := s(name); ok {
+			return value, true
+		}
+	}
+	return "", false
+}
+
+// ArgsSource returns a source reading --key=value command-line arguments.
+// Other arguments are ignored
+func ArgsSource(args []string) PropertySource {
+	values := make(map[string]string)
+	for _, arg := range args {
+		if !strings.HasPrefix(arg, "--") {
+			continue
+		}
+		if key, value, ok := strings.Cut(arg[2:], "="); ok {
+			values[key] = value
+		}
+	}
+	return MapSource(values)
+}
+
+// DotEnvFile returns a source reading KEY=VALUE lines from a .env file.
+// Keys are looked up like environment variables
+func DotEnvFile(path string) (PropertySource, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		text = strings.TrimPrefix(text, "export ")
+		name, value, ok := strings.Cut(text, "=")
+		if !ok {
+			return nil, fmt.Errorf("%s:%d: expected KEY=VALUE", path, line)
+		}
+		values[strings.TrimSpace(name)] = unquote(strings.TrimSpace(value))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return envSource(func(name string) (string, bool) {
+		value, ok := values[name]
+		return value, ok
+	}), nil
+}
+
+// JSONFile returns a source reading a JSON object, nested objects giving
+// dotted keys and arrays of scalars comma separated values
+func JSONFile(path string) (PropertySource, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var document map[string]interface{}
+	if err := json.Unmarshal(data, &document); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	values := make(map[string]string)
+	flatten("", document, values)
+	return MapSource(values), nil
+}
+
+// flatten stores the scalars of a decoded JSON value under dotted keys
+func flatten(prefix string, value interface{}, values map[string]string) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			if prefix != "" {
+				key = prefix + "." + key
+			}
+			flatten(key, child, values)
+		}
+	case []interface{}:
+		items := make([]string, len(v))
+		for i, item := range v {
+			items[i] = fmt.Sprint(item)
+		}
+		values[prefix] = strings.Join(items, ",")
+	case nil:
+		values[prefix] = ""
+	case float64:
+		values[prefix] = strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		values[prefix] = fmt.Sprint(v)
+	}
+}
+
+// YAMLFile returns a source reading a YAML file made of nested mappings,
+// scalars and lists of scalars, which is what configuration files use.
+// Nested mappings give dotted keys and lists comma separated values, so list
+// items may not contain commas. Lists are either block lists, indented or
+// not, or flow lists such as [a, "b"]. Anchors, multi-line scalars, flow
+// mappings and lists of mappings are not supported
+func YAMLFile(path string) (PropertySource, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	values, err := parseYAML(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return MapSource(values), nil
+}
+
+// parseYAML parses the YAML subset supported by YAMLFile
+func parseYAML(data []byte) (map[string]string, error) {
+	type level struct {
+		indent int
+		key    string
+	}
+
+	values := make(map[string]string)
+	var stack []level // enclosing mappings
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		raw := stripComment(scanner.Text())
+		text := strings.TrimSpace(raw)
+		if text == "" || text == "---" {
+			continue
+		}
+		indent := len(raw) - len(strings.TrimLeft(raw, " "))
+
+		// Leave the mappings this line is not nested in. Block list items
+		// may be at the indentation of their key
+		item, isItem := strings.CutPrefix(text, "- ")
+		isItem = isItem || text == "-"
+		for len(stack) > 0 && (stack[len(stack)-1].indent > indent || stack[len(stack)-1].indent == indent && !isItem) {
+			stack = stack[:len(stack)-1]
+		}
+		parent := ""
+		if len(stack) > 0 {
+			parent = stack[len(stack)-1].key
+		}
+
+		// List items are appended to the value of the enclosing key
+		if isItem {
+			if parent == "" {
+				return nil, fmt.Errorf("line %d: list item outside of a mapping", line)
+			}
+			item = unquote(strings.TrimSpace(item))
+			if strings.Contains(item, ",") {
+				return nil, fmt.Errorf("line %d: list item %q contains a comma", line, item)
+			}
+			if _, ok := values[parent]; ok {
+				values[parent] += ","
+			}
+			values[parent] += item
+			continue
+		}
+
+		key, value, ok := strings.Cut(text, ":")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected key: value", line)
+		}
+		key = unquote(strings.TrimSpace(key))
+		if parent != "" {
+			key = parent + "." + key
+		}
+
+		value = strings.TrimSpace(value)
+		switch {
+		case value == "":
+			// Nested mapping or list
+			stack = append(stack, level{indent: indent, key: key})
+		case strings.HasPrefix(value, "[") && strings.HasSuffix(value, "]"):
+			items, err := splitFlowList(value[1 : len(value)-1])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			}
+			values[key] = strings.Join(items, ",")
+		default:
+			values[key] = unquote(value)
+		}
+	}
+	return values, scanner.Err()
+}
+
+// splitFlowList returns the unquoted items of a YAML flow list, without its
+// brackets. Commas inside quotes do not separate items
+func splitFlowList(list string) ([]string, error) {
+	if strings.TrimSpace(list) == "" {
+		return nil, nil
+	}
+
+	var items []string
+	start, inQuote := 0, rune(0)
+	for i, r := range list + "," {
+		switch {
+		case inQuote != 0:
+			if r == inQuote {
+				inQuote = 0
+			}
+		case r == '"' || r == '\'':
+			inQuote = r
+		case r == ',':
+			item := unquote(strings.TrimSpace(list[start:i]))
+			if strings.Contains(item, ",") {
+				return nil, fmt.Errorf("list item %q contains a comma", item)
+			}
+			items = append(items, item)
+			start = i + 1
+		}
+	}
+	if inQuote != 0 {
+		return nil, fmt.Errorf("unterminated quote in list [%s]", list)
+	}
+	return items, nil
+}
+
+// stripComment removes a trailing # comment from a YAML line
+func stripComment(line string) string {
+	inQuote := rune(0)
+	for i, r := range line {
+		switch {
+		case inQuote != 0:
+			if r == inQuote {
+				inQuote = 0
+			}
+		case r == '"' || r == '\'':
+			inQuote = r
+		case r == '#' && (i == 0 || line[i-1] == ' '):
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// unquote removes the quotes around a value, if any
+func unquote(value string) string {
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		if value[0] == '"' {
+			if s, err := strconv.Unquote(value); err == nil {
+				return s
+			}
+		}
+		return value[1 : len(value)-1]
+	}
+	return value
+}
+
+// MustSource returns source, panicking if err is not nil. It simplifies
+// building properties from files that must exist
+func MustSource(source PropertySource, err error) PropertySource {
+	if err != nil {
+		panic(fmt.Sprintf("ctxboot: %v", err))
+	}
+	return source
+}
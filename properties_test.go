@@ -0,0 +1,208 @@
+package ctxboot
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseYAML(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		want    map[string]string
+		wantErr string
+	}{
+		{
+			name: "nested mappings",
+			yaml: "server:\n  port: 8080\n  tls:\n    enabled: true\nname: app\n",
+			want: map[string]string{"server.port": "8080", "server.tls.enabled": "true", "name": "app"},
+		},
+		{
+			name: "comments and quotes",
+			yaml: "---\n# header\nurl: \"http://x/#a\" # comment\nmode: 'dev'\n",
+			want: map[string]string{"url": "http://x/#a", "mode": "dev"},
+		},
+		{
+			name: "indented block list",
+			yaml: "hosts:\n  - a\n  - \"b\"\nport: 1\n",
+			want: map[string]string{"hosts": "a,b", "port": "1"},
+		},
+		{
+			name: "block list at the key's indentation",
+			yaml: "hosts:\n- a\n- b\nport: 1\n",
+			want: map[string]string{"hosts": "a,b", "port": "1"},
+		},
+		{
+			name: "nested block list at the key's indentation",
+			yaml: "db:\n  hosts:\n  - a\n  - b\n  port: 1\n",
+			want: map[string]string{"db.hosts": "a,b", "db.port": "1"},
+		},
+		{
+			name: "empty first item",
+			yaml: "tags:\n- ''\n- b\n",
+			want: map[string]string{"tags": ",b"},
+		},
+		{
+			name: "flow list",
+			yaml: "hosts: [a, 'b', \"c\"]\nempty: []\n",
+			want: map[string]string{"hosts": "a,b,c", "empty": ""},
+		},
+		{
+			name:    "comma inside a quoted flow item",
+			yaml:    "hosts: [a, 'b,c']\n",
+			wantErr: `line 1: list item "b,c" contains a comma`,
+		},
+		{
+			name:    "comma inside a block item",
+			yaml:    "hosts:\n  - 'b,c'\n",
+			wantErr: `line 2: list item "b,c" contains a comma`,
+		},
+		{
+			name:    "unterminated quote",
+			yaml:    "hosts: [a, 'b]\n",
+			wantErr: "line 1: unterminated quote",
+		},
+		{
+			name:    "list item outside of a mapping",
+			yaml:    "- a\n",
+			wantErr: "line 1: list item outside of a mapping",
+		},
+		{
+			name:    "missing colon",
+			yaml:    "server\n",
+			wantErr: "line 1: expected key: value",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseYAML([]byte(tt.yaml))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("parseYAML() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseYAML() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseYAML() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEnvSource(t *testing.T) {
+	t.Setenv("DB_MAX_CONNS", "10")
+	t.Setenv("SERVER_READTIMEOUT", "5s")
+
+	source := EnvSource()
+	tests := []struct {
+		key    string
+		want   string
+		wantOK bool
+	}{
+		{"db.max-conns", "10", true},
+		{"db.max_conns", "10", true},
+		{"server.read-timeout", "5s", true},
+		{"server.port", "", false},
+	}
+	for _, tt := range tests {
+		if got, ok := source.Property(tt.key); got != tt.want || ok != tt.wantOK {
+			t.Errorf("Property(%q) = %q, %v, want %q, %v", tt.key, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestArgsSource(t *testing.T) {
+	source := ArgsSource([]string{"--server.port=8080", "-v", "--debug", "positional", "--name=a=b", "--empty="})
+	tests := []struct {
+		key    string
+		want   string
+		wantOK bool
+	}{
+		{"server.port", "8080", true},
+		{"name", "a=b", true},
+		{"empty", "", true},
+		{"debug", "", false},
+		{"v", "", false},
+	}
+	for _, tt := range tests {
+		if got, ok := source.Property(tt.key); got != tt.want || ok != tt.wantOK {
+			t.Errorf("Property(%q) = %q, %v, want %q, %v", tt.key, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestDotEnvFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".env")
+	content := "# comment\n\nDB_HOST=localhost\nexport DB_PORT = 5432\nGREETING=\"hello world\"\n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	source, err := DotEnvFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for key, want := range map[string]string{"db.host": "localhost", "db.port": "5432", "greeting": "hello world"} {
+		if got, ok := source.Property(key); !ok || got != want {
+			t.Errorf("Property(%q) = %q, %v, want %q", key, got, ok, want)
+		}
+	}
+
+	if err := os.WriteFile(path, []byte("DB_HOST=localhost\nINVALID\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := DotEnvFile(path); err == nil || !strings.Contains(err.Error(), ":2: expected KEY=VALUE") {
+		t.Errorf("DotEnvFile() error = %v, want a line 2 error", err)
+	}
+}
+
+func TestConvertProperty(t *testing.T) {
+	port := 8080
+	tests := []struct {
+		raw     string
+		want    interface{}
+		wantErr bool
+	}{
+		{"text", "text", false},
+		{"true", true, false},
+		{"yes", false, true},
+		{"-42", -42, false},
+		{"0x10", int64(16), false},
+		{"300", int8(0), true},
+		{"7", uint16(7), false},
+		{"-1", uint(0), true},
+		{"1.5", 1.5, false},
+		{"1m30s", 90 * time.Second, false},
+		{"forever", time.Duration(0), true},
+		{"8080", &port, false},
+		{"a, b ,c", []string{"a", "b", "c"}, false},
+		{"", []int{}, false},
+		{"1,x", []int(nil), true},
+		{"127.0.0.1", net.IPv4(127, 0, 0, 1), false},
+	}
+	for _, tt := range tests {
+		typ := reflect.TypeOf(tt.want)
+		got, err := convertProperty(tt.raw, typ)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("convertProperty(%q, %v) = %v, want an error", tt.raw, typ, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("convertProperty(%q, %v) error = %v", tt.raw, typ, err)
+			continue
+		}
+		if !reflect.DeepEqual(got.Interface(), tt.want) {
+			t.Errorf("convertProperty(%q, %v) = %v, want %v", tt.raw, typ, got.Interface(), tt.want)
+		}
+	}
+}
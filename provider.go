@@ -0,0 +1,106 @@
+package ctxboot
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"runtime"
+)
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// funcName returns the qualified name of a function, e.g. main.NewDB
+func funcName(fn reflect.Value) string {
+	if f := runtime.FuncForPC(fn.Pointer()); f != nil {
+		return f.Name()
+	}
+	return fn.Type().String()
+}
+
+// SetProvider registers a provider function creating a component, typically
+// one of a third-party type that cannot be annotated, e.g.
+//
+//	func NewDB(cfg *Config) (*sql.DB, error)
+//
+// The component is registered under the first result type of fn, which may be
+// followed by an error. InitializeComponents calls fn once the components its
+// parameters ask for are initialized, resolving each parameter like an inject
+// field
+func (c *CtxbootComponentContext) SetProvider(fn interface{}) error {
+	return c.SetNamedProvider("", fn)
+}
+
+// SetNamedProvider is like SetProvider for a component registered under a
+// qualifier name
+func (c *CtxbootComponentContext) SetNamedProvider(name string, fn interface{}) error {
+	if fn == nil {
+		return errors.New("cannot store nil provider")
+	}
+
+	fnVal := reflect.ValueOf(fn)
+	fnType := fnVal.Type()
+	if fnType.Kind() != reflect.Func {
+		return fmt.Errorf("provider must be a function: %v", fnType)
+	}
+	if fnType.IsVariadic() {
+		return fmt.Errorf("provider must not be variadic: %v", fnType)
+	}
+	if fnType.NumOut() == 0 || fnType.NumOut() > 2 || (fnType.NumOut() == 2 && fnType.Out(1) != errorType) {
+		return fmt.Errorf("provider must return a component, optionally followed by an error: %v", fnType)
+	}
+	if kind := fnType.Out(0).Kind(); kind != reflect.Ptr && kind != reflect.Interface {
+		return fmt.Errorf("provider must return a pointer or an interface: %v", fnType)
+	}
+
+	// Store the provider (overwriting any component or provider of the type)
+	key := componentKey{typ: fnType.Out(0), name: name}
+	c.mu.Lock()
+	reinject, err := c.checkRegistration(key)
+	if err != nil {
+		c.mu.Unlock()
+		return err
+	}
+	if !c.registered(key) {
+		c.order = append(c.order, key)
+	}
+	c.forgetInitialized(key)
+	delete(c.components, key)
+	delete(c.prototypes, key)
+	c.providers[key] = fnVal
+	c.mu.Unlock()
+
+	c.notify(func(o Observer) { o.OnRegister(key.id()) })
+	if reinject {
+		return c.reinject(key)
+	}
+	return nil
+}
+
+// callProvider resolves the parameters of a provider function, calls it and
+// returns the component it created
+func (c *CtxbootComponentContext) callProvider(fn reflect.Value) (interface{}, error) {
+	fnType := fn.Type()
+
+	args := make([]reflect.Value, fnType.NumIn())
+	for i := range args {
+		arg, err := c.fieldValue(fnType.In(i), injectTag{})
+		if err != nil {
+			return nil, withPath(err, fmt.Sprintf("%s#%d", funcName(fn), i))
+		}
+		args[i] = arg
+	}
+
+	results := fn.Call(args)
+	if len(results) == 2 && !results[1].IsNil() {
+		return nil, fmt.Errorf("provider failed: %w", results[1].Interface().(error))
+	}
+
+	component := results[0]
+	switch component.Kind() {
+	case reflect.Ptr, reflect.Interface, reflect.Map, reflect.Slice, reflect.Func, reflect.Chan:
+		if component.IsNil() {
+			return nil, errors.New("provider returned nil")
+		}
+	}
+	return component.Interface(), nil
+}
@@ -0,0 +1,79 @@
+package ctxboot
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// SetPrototype registers a prototype component: instead of sharing a single
+// instance, every lookup and every injection point gets a new instance
+// created by constructor, with its dependencies injected and its Init hook
+// run. typ must be a pointer to a struct
+func (c *CtxbootComponentContext) SetPrototype(typ reflect.Type, constructor func() interface{}) error {
+	return c.SetNamedPrototype("", typ, constructor)
+}
+
+// SetNamedPrototype is like SetPrototype for a component registered under a
+// qualifier name
+func (c *CtxbootComponentContext) SetNamedPrototype(name string, typ reflect.Type, constructor func() interface{}) error {
+	if constructor == nil {
+		return errors.New("cannot store nil prototype constructor")
+	}
+	if typ.Kind() != reflect.Ptr || typ.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("prototype must be a pointer to a struct: %v", typ)
+	}
+
+	// Store the constructor (overwriting any component or provider of the type)
+	key := componentKey{typ: typ, name: name}
+	c.mu.Lock()
+	reinject, err := c.checkRegistration(key)
+	if err != nil {
+		c.mu.Unlock()
+		return err
+	}
+	if !c.registered(key) {
+		c.order = append(c.order, key)
+	}
+	c.forgetInitialized(key)
+	delete(c.components, key)
+	delete(c.providers, key)
+	c.prototypes[key] = constructor
+	c.mu.Unlock()
+
+	c.notify(func(o Observer) { o.OnRegister(key.id()) })
+	if reinject {
+		return c.reinject(key)
+	}
+	return nil
+}
+
+// isPrototype reports whether the component registered under key is a
+// prototype
+func (c *CtxbootComponentContext) isPrototype(key componentKey) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	_, ok := c.prototypes[key]
+	return ok
+}
+
+// newPrototype creates, injects and initializes a new prototype instance
+func (c *CtxbootComponentContext) newPrototype(key componentKey, constructor func() interface{}) (_ interface{}, err error) {
+	start := c.initStarted(key)
+	defer func() { c.initDone(key, start, err) }()
+
+	instance := constructor()
+	if instance == nil || reflect.TypeOf(instance) != key.typ {
+		return nil, newError(ErrNotAssignable, key, "prototype constructor returned %T", instance)
+	}
+	if err := c.bindProperties(key, instance); err != nil {
+		return nil, err
+	}
+	if err := c.injectDependencies(key, instance); err != nil {
+		return nil, err
+	}
+	if err := runInitHook(instance); err != nil {
+		return nil, fmt.Errorf("failed to create prototype %v: init hook: %w", key, err)
+	}
+	return instance, nil
+}
@@ -0,0 +1,206 @@
+package ctxboot
+
+import "fmt"
+
+// State is the lifecycle state of a context
+type State int
+
+const (
+	// StateRegistering is the state of a new context, accepting registrations.
+	// A context whose initialization failed returns to it, keeping the
+	// components initialized so far: the next InitializeComponents does not
+	// initialize them again, unless they were replaced meanwhile
+	StateRegistering State = iota
+	// StateInitializing is the state of a context during InitializeComponents
+	StateInitializing
+	// StateInitialized is the state of a successfully initialized context,
+	// which is sealed: registrations fail with ErrContextSealed
+	StateInitialized
+	// StateShutDown is the state of a context after Shutdown
+	StateShutDown
+)
+
+// String returns the name of the state
+func (s State) String() string {
+	switch s {
+	case StateRegistering:
+		return "registering"
+	case StateInitializing:
+		return "initializing"
+	case StateInitialized:
+		return "initialized"
+	case StateShutDown:
+		return "shut down"
+	}
+	return fmt.Sprintf("State(%d)", int(s))
+}
+
+// State returns the lifecycle state of c
+func (c *CtxbootComponentContext) State() State {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.state
+}
+
+// IsInitialized reports whether InitializeComponents succeeded and c was not
+// shut down since
+func (c *CtxbootComponentContext) IsInitialized() bool {
+	return c.State() == StateInitialized
+}
+
+// EnableUnsafeReinjection lets registrations proceed once c is initialized.
+// A component registered then is initialized at once, and the components it
+// may be injected into are injected again, those created by providers being
+// created again. Marking a component primary or setting its order or config
+// prefix injects them again too. A registration that leaves one of them
+// ambiguous or unresolvable fails with the resolution error, the
+// registration itself being kept. Re-injected components do not run their
+// Init hook again and replaced instances are not stopped, so components that
+// copied a dependency at initialization keep the stale one
+func (c *CtxbootComponentContext) EnableUnsafeReinjection() {
+	c.mu.Lock()
+	c.unsafeReinjection = true
+	c.mu.Unlock()
+}
+
+// checkRegistration reports whether a registration under key may proceed,
+// and whether it requires re-injection. Caller must hold c.mu
+func (c *CtxbootComponentContext) checkRegistration(key componentKey) (reinject bool, err error) {
+	switch {
+	case c.state == StateRegistering:
+		return false, nil
+	case c.state == StateInitialized && c.unsafeReinjection:
+		return true, nil
+	}
+	return false, newError(ErrContextSealed, key, "context is %v", c.state)
+}
+
+// reinject initializes the component registered under key once c is
+// initialized, and injects again the components it may be injected into,
+// see refreshDependents
+func (c *CtxbootComponentContext) reinject(key componentKey) error {
+	if !c.isPrototype(key) {
+		if err := c.initializeComponent(key); err != nil {
+			return err
+		}
+	}
+
+	c.mu.Lock()
+	if _, isPrototype := c.prototypes[key]; !isPrototype && !containsKey(c.initOrder, key) {
+		c.initOrder = append(c.initOrder, key)
+	}
+	c.mu.Unlock()
+	return c.refreshDependents(key)
+}
+
+// refreshDependents injects again the initialized components with an
+// injection point the component registered under key may resolve to, since
+// registering it or changing its primary mark or order may change what the
+// point resolves to. A point that became ambiguous or unresolvable fails
+// with its resolution error
+func (c *CtxbootComponentContext) refreshDependents(key componentKey) error {
+	c.mu.RLock()
+	order := append([]componentKey(nil), c.initOrder...)
+	c.mu.RUnlock()
+
+	// Components are visited in initialization order, so a re-created
+	// provided component is injected into its own dependents
+	changed := map[componentKey]bool{key: true}
+	for _, dependent := range order {
+		if changed[dependent] {
+			continue
+		}
+		affected, err := c.injectsAny(dependent, changed)
+		if err != nil {
+			return err
+		}
+		if !affected {
+			continue
+		}
+
+		recreated, err := c.refreshComponent(dependent)
+		if err != nil {
+			return err
+		}
+		if recreated {
+			changed[dependent] = true
+		}
+	}
+	return nil
+}
+
+// injectsAny reports whether one of the injection points of the component
+// registered under key may resolve to a component in set. Deferred points
+// resolve on use and are left out
+func (c *CtxbootComponentContext) injectsAny(key componentKey, set map[componentKey]bool) (bool, error) {
+	points, err := c.injectionPoints(key)
+	if err != nil {
+		return false, err
+	}
+	for _, point := range points {
+		if point.deferred || point.valueType == publisherType {
+			continue
+		}
+		valueType, name := point.valueType, point.opts.name
+		if elemType, ok := collectionElem(valueType); ok {
+			valueType, name = elemType, ""
+		}
+		for k := range set {
+			if k.satisfies(lookupType(valueType), name) {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// refreshComponent injects again the initialized component registered under
+// key, whose dependencies changed. A provided component is created and
+// initialized again instead, and refreshComponent reports it was recreated
+func (c *CtxbootComponentContext) refreshComponent(key componentKey) (recreated bool, err error) {
+	c.mu.Lock()
+	instance := c.components[key]
+	_, isProvided := c.providers[key]
+	if isProvided {
+		delete(c.components, key)
+	}
+	c.mu.Unlock()
+
+	if isProvided {
+		return true, c.initializeComponent(key)
+	}
+	if err := c.injectDependencies(key, instance); err != nil {
+		return false, fmt.Errorf("failed to re-inject component %v: %w", key, err)
+	}
+	return false, nil
+}
+
+// containsKey reports whether keys contains key
+func containsKey(keys []componentKey, key componentKey) bool {
+	for _, k := range keys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// withoutKey returns keys without key
+func withoutKey(keys []componentKey, key componentKey) []componentKey {
+	for i, k := range keys {
+		if k == key {
+			return append(keys[:i:i], keys[i+1:]...)
+		}
+	}
+	return keys
+}
+
+// containsAny reports whether one of keys is in set
+func containsAny(keys []componentKey, set map[componentKey]bool) bool {
+	for _, k := range keys {
+		if set[k] {
+			return true
+		}
+	}
+	return false
+}
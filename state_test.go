@@ -0,0 +1,219 @@
+package ctxboot
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type retryHealthy struct {
+	inits int
+}
+
+func (h *retryHealthy) Init() error {
+	h.inits++
+	return nil
+}
+
+type retryFlaky struct {
+	Healthy *retryHealthy `ctxboot:"inject"`
+	fail    bool
+	inits   int
+}
+
+func (f *retryFlaky) Init() error {
+	f.inits++
+	if f.fail {
+		return errors.New("not ready")
+	}
+	return nil
+}
+
+type retryDependent struct {
+	Healthy *retryHealthy `ctxboot:"inject"`
+}
+
+func TestInitializeComponentsRetry(t *testing.T) {
+	for _, workers := range []int{1, 4} {
+		c := NewCtxbootComponentContext()
+		c.SetInitWorkers(workers)
+		healthy := &retryHealthy{}
+		flaky := &retryFlaky{fail: true}
+		mustRegister(t, c, healthy)
+		mustRegister(t, c, flaky)
+
+		if err := c.InitializeComponents(); err == nil {
+			t.Fatalf("workers=%d: InitializeComponents() succeeded, want the init hook error", workers)
+		}
+		if c.State() != StateRegistering {
+			t.Fatalf("workers=%d: State() = %v after a failure, want registering", workers, c.State())
+		}
+
+		flaky.fail = false
+		if err := c.InitializeComponents(); err != nil {
+			t.Fatalf("workers=%d: InitializeComponents() = %v", workers, err)
+		}
+		if healthy.inits != 1 || flaky.inits != 2 {
+			t.Errorf("workers=%d: Init ran %d and %d times, want 1 and 2", workers, healthy.inits, flaky.inits)
+		}
+		if want := []componentKey{{typ: reflect.TypeOf(healthy)}, {typ: reflect.TypeOf(flaky)}}; !reflect.DeepEqual(c.initOrder, want) {
+			t.Errorf("workers=%d: initOrder = %v, want %v", workers, c.initOrder, want)
+		}
+	}
+}
+
+func TestInitializeComponentsRetryReplaced(t *testing.T) {
+	c := NewCtxbootComponentContext()
+	healthy := &retryHealthy{}
+	dependent := &retryDependent{}
+	flaky := &retryFlaky{fail: true}
+	mustRegister(t, c, healthy)
+	mustRegister(t, c, dependent)
+	mustRegister(t, c, flaky)
+	if err := c.InitializeComponents(); err == nil {
+		t.Fatal("InitializeComponents() succeeded, want the init hook error")
+	}
+
+	// The replacement is initialized and injected into the resumed dependent
+	replacement := &retryHealthy{}
+	mustRegister(t, c, replacement)
+	flaky.fail = false
+	if err := c.InitializeComponents(); err != nil {
+		t.Fatalf("InitializeComponents() = %v", err)
+	}
+	if healthy.inits != 1 || replacement.inits != 1 {
+		t.Errorf("Init ran %d and %d times, want 1 and 1", healthy.inits, replacement.inits)
+	}
+	if dependent.Healthy != replacement || flaky.Healthy != replacement {
+		t.Error("the replacement was not injected into its dependents")
+	}
+}
+
+func mustRegister(t *testing.T, c *CtxbootComponentContext, instance interface{}) {
+	t.Helper()
+	if err := c.SetComponent(reflect.TypeOf(instance), instance); err != nil {
+		t.Fatal(err)
+	}
+}
+
+type reinjectDB interface {
+	Query() string
+}
+
+type reinjectPG struct{}
+
+func (*reinjectPG) Query() string { return "pg" }
+
+type reinjectMy struct{}
+
+func (*reinjectMy) Query() string { return "my" }
+
+type reinjectRepo struct {
+	D   reinjectDB   `ctxboot:"inject"`
+	All []reinjectDB `ctxboot:"inject"`
+}
+
+type reinjectConfig struct {
+	Port int
+}
+
+type reinjectServer struct {
+	Config reinjectConfig `ctxboot:"inject"`
+}
+
+func newReinjectContext(t *testing.T, instances ...interface{}) *CtxbootComponentContext {
+	t.Helper()
+	c := NewCtxbootComponentContext()
+	for _, instance := range instances {
+		mustRegister(t, c, instance)
+	}
+	if err := c.InitializeComponents(); err != nil {
+		t.Fatal(err)
+	}
+	c.EnableUnsafeReinjection()
+	return c
+}
+
+func TestReinjectAmbiguous(t *testing.T) {
+	repo := &reinjectRepo{}
+	c := newReinjectContext(t, &reinjectPG{}, repo)
+
+	// A second implementation makes the injection point ambiguous
+	my := &reinjectMy{}
+	if err := c.SetComponent(reflect.TypeOf(my), my); !errors.Is(err, ErrAmbiguousComponent) {
+		t.Fatalf("SetComponent() = %v, want ErrAmbiguousComponent", err)
+	}
+
+	if err := c.MarkPrimary(reflect.TypeOf(my)); err != nil {
+		t.Fatalf("MarkPrimary() = %v", err)
+	}
+	if repo.D != my {
+		t.Errorf("Repo.D = %v, want the primary component", repo.D)
+	}
+	if got, err := Get[reinjectDB](c); err != nil || got != my {
+		t.Errorf("Get() = %v, %v, want the primary component", got, err)
+	}
+}
+
+func TestReinjectOrder(t *testing.T) {
+	repo := &reinjectRepo{}
+	pg, my := &reinjectPG{}, &reinjectMy{}
+	c := NewCtxbootComponentContext()
+	mustRegister(t, c, pg)
+	mustRegister(t, c, my)
+	mustRegister(t, c, repo)
+	if err := c.MarkPrimary(reflect.TypeOf(pg)); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.InitializeComponents(); err != nil {
+		t.Fatal(err)
+	}
+	c.EnableUnsafeReinjection()
+
+	if err := c.SetOrder(reflect.TypeOf(pg), 1); err != nil {
+		t.Fatalf("SetOrder() = %v", err)
+	}
+	if len(repo.All) != 2 || repo.All[0] != my || repo.All[1] != pg {
+		t.Errorf("Repo.All = %v, want my before pg", repo.All)
+	}
+}
+
+func TestReinjectConfigPrefix(t *testing.T) {
+	server := &reinjectServer{}
+	c := NewCtxbootComponentContext()
+	c.SetProperties(NewProperties(MapSource(map[string]string{"http.port": "8080"})))
+	mustRegister(t, c, &reinjectConfig{Port: 80})
+	mustRegister(t, c, server)
+	if err := c.InitializeComponents(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The copy injected into the server is refreshed
+	c.EnableUnsafeReinjection()
+	if err := c.SetConfigPrefix(reflect.TypeOf(&reinjectConfig{}), "http"); err != nil {
+		t.Fatalf("SetConfigPrefix() = %v", err)
+	}
+	if server.Config.Port != 8080 {
+		t.Errorf("Server.Config.Port = %d, want 8080", server.Config.Port)
+	}
+}
+
+func TestSealedSetters(t *testing.T) {
+	pg := &reinjectPG{}
+	c := NewCtxbootComponentContext()
+	mustRegister(t, c, pg)
+	if err := c.InitializeComponents(); err != nil {
+		t.Fatal(err)
+	}
+
+	typ := reflect.TypeOf(pg)
+	for name, err := range map[string]error{
+		"MarkPrimary":     c.MarkPrimary(typ),
+		"SetOrder":        c.SetOrder(typ, 1),
+		"SetConfigPrefix": c.SetConfigPrefix(typ, "db"),
+	} {
+		if !errors.Is(err, ErrContextSealed) {
+			t.Errorf("%s() = %v, want ErrContextSealed", name, err)
+		}
+	}
+}
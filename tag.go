@@ -0,0 +1,66 @@
+package ctxboot
+
+import (
+	"reflect"
+	"strings"
+)
+
+// injectTag holds the options of a `ctxboot:"inject,..."` field tag
+type injectTag struct {
+	name     string // qualifier of the component to inject
+	optional bool   // leave the field zero when nothing provides it
+}
+
+// parseInjectTag parses the ctxboot tag of a struct field. The second return
+// value reports whether the field is an injection point at all
+func parseInjectTag(tag reflect.StructTag) (injectTag, bool) {
+	var opts injectTag
+
+	value, ok := tag.Lookup("ctxboot")
+	if !ok {
+		return opts, false
+	}
+
+	parts := strings.Split(value, ",")
+	if strings.TrimSpace(parts[0]) != "inject" {
+		return opts, false
+	}
+
+	for _, part := range parts[1:] {
+		key, val, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch key {
+		case "name":
+			opts.name = val
+		case "optional":
+			opts.optional = true
+		}
+	}
+	return opts, true
+}
+
+// valueTag holds the options of a `ctxboot:"value=key,default=..."` field tag
+type valueTag struct {
+	key        string // property to set the field from
+	def        string // value used when no source defines the property
+	hasDefault bool
+}
+
+// parseValueTag parses the ctxboot tag of a struct field set from a property.
+// The default comes last and may contain commas, e.g. default=a,b,c
+func parseValueTag(tag reflect.StructTag) (valueTag, bool) {
+	var opts valueTag
+
+	value, ok := tag.Lookup("ctxboot")
+	if !ok {
+		return opts, false
+	}
+
+	key, rest, _ := strings.Cut(value, ",")
+	opts.key, ok = strings.CutPrefix(strings.TrimSpace(key), "value=")
+	if !ok {
+		return opts, false
+	}
+
+	opts.def, opts.hasDefault = strings.CutPrefix(strings.TrimSpace(rest), "default=")
+	return opts, true
+}
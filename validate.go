@@ -0,0 +1,97 @@
+package ctxboot
+
+import (
+	"errors"
+	"reflect"
+)
+
+// Validate checks the wiring of the components registered in c without
+// creating, injecting or initializing any of them: every inject field and
+// provider parameter must resolve to exactly one component, unless optional,
+// every value field must have a property or a default converting to its type,
+// config components must bind, and components must not depend on each other
+// in a cycle. All problems are returned together, each as an *Error
+func (c *CtxbootComponentContext) Validate() error {
+	c.mu.RLock()
+	order := append([]componentKey(nil), c.order...)
+	c.mu.RUnlock()
+
+	var errs []error
+	for _, key := range order {
+		errs = append(errs, c.validateComponent(key)...)
+	}
+	// Cycles among the dependencies that resolve
+	for _, cycle := range c.dependencyCycles(order) {
+		errs = append(errs, &Error{Err: ErrCircularDependency, Path: cycle})
+	}
+	return errors.Join(errs...)
+}
+
+// validateComponent checks the injection points of the component registered
+// under key, and its value fields and properties unless it is provided
+func (c *CtxbootComponentContext) validateComponent(key componentKey) []error {
+	points, err := c.injectionPoints(key)
+	if err != nil {
+		return []error{err}
+	}
+
+	var errs []error
+	for _, point := range points {
+		if err := c.validateValue(point.valueType, point.opts); err != nil {
+			errs = append(errs, withPath(err, point.step))
+		}
+	}
+
+	c.mu.RLock()
+	_, isProvided := c.providers[key]
+	c.mu.RUnlock()
+	if isProvided {
+		return errs
+	}
+
+	// Config components are bound to a fresh instance
+	typ, _ := c.componentStruct(key)
+	if err := c.bindProperties(key, reflect.New(typ).Interface()); err != nil {
+		errs = append(errs, withPath(err, key.String()))
+	}
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if opts, ok := parseValueTag(field.Tag); ok {
+			if _, err := c.propertyValue(field.Type, opts); err != nil {
+				errs = append(errs, withPath(err, fieldStep(typ, field.Name)))
+			}
+		}
+	}
+	return errs
+}
+
+// validateValue checks that a value of type valueType resolves like
+// fieldValue would resolve it, without creating prototypes
+func (c *CtxbootComponentContext) validateValue(valueType reflect.Type, opts injectTag) error {
+	if elemType, ok := collectionElem(valueType); ok {
+		if len(c.collectionEntries(lookupType(elemType))) == 0 && !opts.optional {
+			return newError(ErrComponentNotFound, componentKey{typ: lookupType(elemType)}, "no component for %v", valueType)
+		}
+		return nil
+	}
+
+	typ := lookupType(valueType)
+	for ctx := c; ctx != nil; ctx = ctx.parent {
+		ctx.mu.RLock()
+		found := len(ctx.candidates(typ, opts.name)) > 0
+		_, err := ctx.resolve(typ, opts.name)
+		ctx.mu.RUnlock()
+		if found {
+			return err
+		}
+		// The event bus is injected unless a Publisher is registered
+		if typ == publisherType && opts.name == "" {
+			return nil
+		}
+	}
+
+	if opts.optional {
+		return nil
+	}
+	return newError(ErrComponentNotFound, componentKey{typ: typ, name: opts.name}, "")
+}
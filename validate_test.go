@@ -0,0 +1,63 @@
+package ctxboot
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type validDB interface{ Query() }
+
+type validPostgres struct{}
+
+func (*validPostgres) Query() {}
+
+type validMySQL struct{}
+
+func (*validMySQL) Query() {}
+
+type validMissing struct{}
+
+type validService struct {
+	DB       validDB             `ctxboot:"inject"`
+	Missing  *validMissing       `ctxboot:"inject"`
+	Optional *validMissing       `ctxboot:"inject,optional"`
+	Later    Lazy[*validMissing] `ctxboot:"inject"`
+	Events   Publisher           `ctxboot:"inject"`
+	Port     int                 `ctxboot:"value=port"`
+	Host     string              `ctxboot:"value=host,default=localhost"`
+}
+
+func TestValidate(t *testing.T) {
+	c := NewCtxbootComponentContext()
+	service := &validService{}
+	for _, instance := range []interface{}{&validPostgres{}, &validMySQL{}, service, &cycA{}, &cycB{}, &cycC{}} {
+		mustRegister(t, c, instance)
+	}
+
+	err := c.Validate()
+	var got []string
+	for _, err := range err.(interface{ Unwrap() []error }).Unwrap() {
+		var cerr *Error
+		if !errors.As(err, &cerr) {
+			t.Fatalf("%v is not an *Error", err)
+		}
+		got = append(got, cerr.Err.Error()+": "+strings.Join(cerr.Path, " -> "))
+	}
+	want := []string{
+		"ambiguous component: ctxboot.validService.DB -> ctxboot.validDB",
+		"component not found: ctxboot.validService.Missing -> *ctxboot.validMissing",
+		"component not found: ctxboot.validService.Later -> *ctxboot.validMissing",
+		"missing property: ctxboot.validService.Port",
+		"circular dependency: ctxboot.cycA.C -> ctxboot.cycC.A -> *ctxboot.cycA",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Validate() reported\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	// Nothing was set or initialized
+	if !reflect.DeepEqual(service, &validService{}) || c.State() != StateRegistering {
+		t.Errorf("Validate() changed the context: %+v, %v", service, c.State())
+	}
+}